@@ -0,0 +1,93 @@
+package birdsocket
+
+import (
+	"context"
+	"time"
+)
+
+// BatchOption modifies the behavior of QueryBatch
+type BatchOption func(*batch)
+
+type batch struct {
+	continueOnError bool
+}
+
+// ContinueOnError keeps sending the remaining queries of a batch
+// after a query was answered with an error reply
+func ContinueOnError() BatchOption {
+	return func(b *batch) {
+		b.continueOnError = true
+	}
+}
+
+// QueryBatch sends the queries in order on the connection, holding it until
+// the last reply was read. The replies received are returned in the order of the queries.
+// A query answered with an error reply stops the batch and the error is returned as *BirdError,
+// unless ContinueOnError is passed (in which case the first one is returned).
+// Connection errors always stop the batch
+func (s *BirdSocket) QueryBatch(queries []string, opts ...BatchOption) ([][]byte, error) {
+	b := &batch{}
+	for _, o := range opts {
+		o(b)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	replies := make([][]byte, 0, len(queries))
+	var replyErr error
+	for _, qry := range queries {
+		start := time.Now()
+		output, err := s.queryContext(context.Background(), qry)
+		s.observe(qry, start, output, err)
+		if err != nil {
+			return replies, err
+		}
+		replies = append(replies, output)
+
+		if err := replyError(ParseReply(output)); err != nil {
+			if !b.continueOnError {
+				return replies, err
+			}
+
+			if replyErr == nil {
+				replyErr = err
+			}
+		}
+	}
+
+	return replies, replyErr
+}
+
+// Pipeline sends all commands before reading any reply and splits the replies
+// by their final lines, saving a round trip per command compared to QueryBatch.
+// Error replies are returned as part of the replies, see QueryChecked for detecting them.
+// No command is sent if one of them contains interior newlines.
+// If reading a reply fails, the connection is closed since the replies to the
+// remaining commands would be read by the next queries otherwise
+func (s *BirdSocket) Pipeline(cmds []string) ([]*Reply, error) {
+	if len(cmds) == 0 {
+		return []*Reply{}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.send(cmds...); err != nil {
+		return nil, err
+	}
+
+	replies := make([]*Reply, 0, len(cmds))
+	for range cmds {
+		b, err := s.readFromSocket(s.conn)
+		if err != nil {
+			s.closeConn()
+			s.dirty = false
+			return replies, err
+		}
+
+		replies = append(replies, ParseReply(b))
+	}
+
+	return replies, nil
+}
@@ -0,0 +1,138 @@
+package birdsocket
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+var batchReplies = map[string]string{
+	"show status":    "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+	"show protocols": "2002-name     proto    table    state  since       info\n1002-device1  Device   master   up     2018-12-21\n0000 \n",
+	"show memory":    "1018-BIRD memory usage\n  Total:         10 MB\n0000 \n",
+}
+
+// TestQueryBatch verifies that the replies of a batch are separated correctly
+func TestQueryBatch(t *testing.T) {
+	s := connectFakeBird(t, batchReplies)
+
+	queries := []string{"show status", "show protocols", "show memory"}
+	replies, err := s.QueryBatch(queries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("replies", 3, len(replies), t)
+	for i, qry := range queries {
+		assert.StringEqual(qry, batchReplies[qry], string(replies[i]), t)
+	}
+}
+
+// TestQueryBatchError verifies that a batch stops on the first error reply
+// unless ContinueOnError is passed
+func TestQueryBatchError(t *testing.T) {
+	s := connectFakeBird(t, batchReplies)
+	queries := []string{"show status", "show foo", "show memory"}
+
+	replies, err := s.QueryBatch(queries)
+	_, ok := err.(*BirdError)
+	assert.True("*BirdError returned", ok, t)
+	assert.IntEqual("replies", 2, len(replies), t)
+
+	replies, err = s.QueryBatch(queries, ContinueOnError())
+	_, ok = err.(*BirdError)
+	assert.True("*BirdError returned", ok, t)
+	assert.IntEqual("replies", 3, len(replies), t)
+	assert.StringEqual("show memory", batchReplies["show memory"], string(replies[2]), t)
+}
+
+// TestPipeline verifies that the replies of pipelined commands are bounded correctly
+func TestPipeline(t *testing.T) {
+	s := connectFakeBird(t, batchReplies)
+
+	replies, err := s.Pipeline([]string{"show status", "show foo", "show protocols"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("replies", 3, len(replies), t)
+	assert.IntEqual("status lines", 2, len(replies[0].Lines), t)
+	assert.StringEqual("status", "Daemon is up and running", replies[0].terminal().Message, t)
+	assert.IntEqual("error", CodeSyntaxError, replies[1].terminal().Code, t)
+	assert.IntEqual("protocol lines", 3, len(replies[2].Lines), t)
+	assert.IntEqual("protocols", CodeReplyOK, replies[2].terminal().Code, t)
+
+	_, err = s.Pipeline([]string{"show status", "show protocols\nconfigure"})
+	assert.True("ErrMultilineQuery", err == ErrMultilineQuery, t)
+}
+
+// TestPipelineDeadline verifies that the replies to the remaining commands of a pipeline
+// are not returned to the next query if the read deadline was exceeded
+func TestPipelineDeadline(t *testing.T) {
+	s := NewSocket("", WithReadDeadline(50*time.Millisecond), WithDialer(pipeDialer(func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+
+		r := bufio.NewReader(conn)
+		r.ReadString('\n')
+		conn.Write([]byte(batchReplies["show status"]))
+		time.Sleep(200 * time.Millisecond)
+		conn.Write([]byte(batchReplies["show protocols"] + batchReplies["show memory"]))
+
+		r.ReadString('\n')
+		conn.Write([]byte(batchReplies["show status"]))
+	})))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	replies, err := s.Pipeline([]string{"show status", "show protocols", "show memory"})
+	assert.True("ErrDeadlineExceeded", errors.Is(err, ErrDeadlineExceeded), t)
+	assert.IntEqual("replies", 1, len(replies), t)
+	assert.False("connected", s.IsConnected(), t)
+
+	_, err = s.Query("show status", true)
+	assert.True("ErrNotConnected", errors.Is(err, ErrNotConnected), t)
+}
+
+// TestPipelineMergedReplies simulate a scenario in which the replies to all
+// pipelined commands arrive in a single read
+func TestPipelineMergedReplies(t *testing.T) {
+	s := NewSocket("", WithReadDeadline(time.Second), WithDialer(pipeDialer(func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+
+		r := bufio.NewReader(conn)
+		r.ReadString('\n')
+		r.ReadString('\n')
+		r.ReadString('\n')
+		conn.Write([]byte(batchReplies["show status"] + "9001 syntax error\n" + batchReplies["show memory"]))
+
+		r.ReadString('\n')
+		conn.Write([]byte(batchReplies["show protocols"]))
+		r.ReadString('\n')
+	})))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	replies, err := s.Pipeline([]string{"show status", "show foo", "show memory"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("replies", 3, len(replies), t)
+	assert.StringEqual("status", "Daemon is up and running", replies[0].terminal().Message, t)
+	assert.IntEqual("error", CodeSyntaxError, replies[1].terminal().Code, t)
+	assert.IntEqual("memory lines", 3, len(replies[2].Lines), t)
+
+	out, err := s.Query("show protocols", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("next query", batchReplies["show protocols"], string(out), t)
+}
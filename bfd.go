@@ -0,0 +1,73 @@
+package birdsocket
+
+import (
+	"strings"
+	"time"
+)
+
+// BFDSession is a session as listed by `show bfd sessions`.
+// State is the state of the session like Up, Down or Init,
+// Interval is the transmit interval and Timeout the detection time
+// after which the session is considered down if nothing was received
+type BFDSession struct {
+	Protocol  string
+	PeerIP    string
+	Interface string
+	State     string
+	Since     time.Time
+	Interval  time.Duration
+	Timeout   time.Duration
+}
+
+// ShowBFDSessions queries the sessions of the BFD protocol instance.
+// If protocol is empty the sessions of all BFD instances are returned
+func (s *BirdSocket) ShowBFDSessions(protocol string) ([]BFDSession, error) {
+	r, err := s.QueryChecked(strings.TrimSpace("show bfd sessions " + protocol))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseBFDSessions(r, protocol), nil
+}
+
+func parseBFDSessions(r *Reply, protocol string) []BFDSession {
+	sessions := make([]BFDSession, 0)
+
+	for _, l := range r.Lines {
+		if l.Code != CodeBFDSessions {
+			continue
+		}
+
+		msg := strings.TrimSpace(l.Message)
+		if strings.HasPrefix(msg, "IP address") || len(msg) == 0 {
+			continue
+		}
+
+		f := strings.Fields(msg)
+		if len(f) == 1 && strings.HasSuffix(msg, ":") {
+			protocol = strings.TrimSuffix(msg, ":")
+			continue
+		}
+
+		if sess, ok := parseBFDSessionLine(f); ok {
+			sess.Protocol = protocol
+			sessions = append(sessions, sess)
+		}
+	}
+
+	return sessions
+}
+
+// parseBFDSessionLine parses lines like `192.168.1.2  eth0  Up  2021-02-28 10:00:00  0.100  0.500`
+func parseBFDSessionLine(f []string) (BFDSession, bool) {
+	if len(f) < 5 {
+		return BFDSession{}, false
+	}
+
+	sess := BFDSession{PeerIP: f[0], Interface: f[1], State: f[2]}
+	sess.Since, _ = parseSince(f[3 : len(f)-2])
+	sess.Interval = parseDeadTime(f[len(f)-2])
+	sess.Timeout = parseDeadTime(f[len(f)-1])
+
+	return sess, true
+}
@@ -0,0 +1,66 @@
+package birdsocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestShowBFDSessions verifies parsing of `show bfd sessions` for a single instance
+func TestShowBFDSessions(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show bfd sessions bfd1": "1020-bfd1:\n" +
+			" IP address                Interface  State      Since         Interval  Timeout\n" +
+			" 192.168.1.2               eth0       Up         2021-02-28 10:00:00    0.100    0.500\n" +
+			" 2001:db8::2               eth1       Down       2021-02-28 11:30:00    1.000    0.000\n" +
+			"0000 \n",
+	})
+
+	sessions, err := s.ShowBFDSessions("bfd1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("sessions", 2, len(sessions), t)
+
+	sess := sessions[0]
+	assert.StringEqual("protocol", "bfd1", sess.Protocol, t)
+	assert.StringEqual("peer", "192.168.1.2", sess.PeerIP, t)
+	assert.StringEqual("interface", "eth0", sess.Interface, t)
+	assert.StringEqual("state", "Up", sess.State, t)
+	assert.True("since", sess.Since.Equal(time.Date(2021, 2, 28, 10, 0, 0, 0, time.Local)), t)
+	assert.True("interval", sess.Interval == 100*time.Millisecond, t)
+	assert.True("timeout", sess.Timeout == 500*time.Millisecond, t)
+
+	sess = sessions[1]
+	assert.StringEqual("peer", "2001:db8::2", sess.PeerIP, t)
+	assert.StringEqual("state", "Down", sess.State, t)
+	assert.True("interval", sess.Interval == time.Second, t)
+}
+
+// TestShowBFDSessionsAll verifies that sessions of all instances are returned
+// along with the instance they belong to
+func TestShowBFDSessionsAll(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show bfd sessions": "1020-bfd1:\n" +
+			" IP address                Interface  State      Since         Interval  Timeout\n" +
+			" 192.168.1.2               eth0       Up         10:00:00    0.100    0.500\n" +
+			"1020-\n" +
+			" bfd2:\n" +
+			" IP address                Interface  State      Since         Interval  Timeout\n" +
+			" 10.0.0.2                  eth2       Init       10:00:00    1.000    3.000\n" +
+			"0000 \n",
+	})
+
+	sessions, err := s.ShowBFDSessions("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("sessions", 2, len(sessions), t)
+	assert.StringEqual("first protocol", "bfd1", sessions[0].Protocol, t)
+	assert.StringEqual("second protocol", "bfd2", sessions[1].Protocol, t)
+	assert.StringEqual("state", "Init", sessions[1].State, t)
+	assert.True("timeout", sessions[1].Timeout == 3*time.Second, t)
+}
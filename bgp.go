@@ -0,0 +1,88 @@
+package birdsocket
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BGPDetail holds the details of a BGP session as listed by `show protocols all`.
+// HoldTime and KeepaliveTime are the negotiated timers of an established session
+type BGPDetail struct {
+	Protocol        Protocol
+	BGPState        string
+	NeighborAddress string
+	NeighborAS      int
+	NeighborID      string
+	LocalAS         int
+	Session         string
+	SourceAddress   string
+	HoldTime        time.Duration
+	KeepaliveTime   time.Duration
+	Routes          *RouteCounts
+}
+
+// ShowProtocolDetail queries the details of the BGP protocol instance name
+func (s *BirdSocket) ShowProtocolDetail(name string) (*BGPDetail, error) {
+	p, r, err := s.showProtocolAll(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Proto != "BGP" {
+		return nil, fmt.Errorf("protocol %s is not a BGP protocol: %s", name, p.Proto)
+	}
+
+	d := &BGPDetail{Protocol: *p}
+	if p.Detail != nil {
+		d.Routes = p.Detail.Routes
+	}
+
+	for _, l := range r.Lines {
+		if l.Code == CodeProtocolDetails {
+			parseBGPDetailLine(l.Message, d)
+		}
+	}
+
+	return d, nil
+}
+
+func parseBGPDetailLine(line string, d *BGPDetail) {
+	key, value := splitKeyValue(line)
+
+	switch key {
+	case "BGP state":
+		d.BGPState = value
+	case "Neighbor address":
+		d.NeighborAddress = value
+	case "Neighbor AS":
+		d.NeighborAS, _ = strconv.Atoi(value)
+	case "Neighbor ID":
+		d.NeighborID = value
+	case "Local AS":
+		d.LocalAS, _ = strconv.Atoi(value)
+	case "Session":
+		d.Session = value
+	case "Source address":
+		d.SourceAddress = value
+	case "Hold timer":
+		d.HoldTime = parseTimer(value)
+	case "Keepalive timer":
+		d.KeepaliveTime = parseTimer(value)
+	}
+}
+
+// parseTimer parses the configured time of timers like `140.145/240` or `140/240`
+func parseTimer(s string) time.Duration {
+	if i := strings.Index(s, "/"); i >= 0 {
+		s = s[i+1:]
+	}
+
+	sec, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+
+	return time.Duration(sec * float64(time.Second))
+}
@@ -0,0 +1,75 @@
+package birdsocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestShowProtocolDetail verifies parsing of a BGP session as listed by BIRD 2.0
+func TestShowProtocolDetail(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show protocols all upstream1": "2002-Name       Proto      Table      State  Since         Info\n" +
+			"1002-upstream1  BGP        ---        up     2021-02-28 09:00:05  Established   \n" +
+			"1006-  Description:    Upstream provider\n" +
+			"  BGP state:          Established\n" +
+			"    Neighbor address: 192.168.1.2\n" +
+			"    Neighbor AS:      65001\n" +
+			"    Local AS:         65000\n" +
+			"    Neighbor ID:      192.168.1.2\n" +
+			"    Local capabilities\n" +
+			"      Multiprotocol\n" +
+			"        AF announced: ipv4\n" +
+			"      Route refresh\n" +
+			"      Graceful restart\n" +
+			"      4-octet AS numbers\n" +
+			"    Neighbor capabilities\n" +
+			"      Multiprotocol\n" +
+			"        AF announced: ipv4\n" +
+			"      Route refresh\n" +
+			"      4-octet AS numbers\n" +
+			"    Session:          external AS4\n" +
+			"    Source address:   192.168.1.1\n" +
+			"    Hold timer:       140.145/240\n" +
+			"    Keepalive timer:  48.588/80\n" +
+			"  Channel ipv4\n" +
+			"    State:          UP\n" +
+			"    Table:          master4\n" +
+			"    Preference:     100\n" +
+			"    Input filter:   import_upstream\n" +
+			"    Output filter:  export_upstream\n" +
+			"    Routes:         650000 imported, 10 exported, 640000 preferred\n" +
+			"    Route change stats:     received   rejected   filtered    ignored   accepted\n" +
+			"      Import updates:         700000          0         12          0     650000\n" +
+			"      Import withdraws:         1000          0        ---          0       1000\n" +
+			"    BGP Next hop:   192.168.1.1\n" +
+			"\n" +
+			"0000 \n",
+		"show protocols all device1": "2002-Name       Proto      Table      State  Since         Info\n" +
+			"1002-device1    Device     ---        up     2021-02-28 09:00:00\n" +
+			"\n" +
+			"0000 \n",
+	})
+
+	d, err := s.ShowProtocolDetail("upstream1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("name", "upstream1", d.Protocol.Name, t)
+	assert.StringEqual("bgp state", "Established", d.BGPState, t)
+	assert.StringEqual("neighbor address", "192.168.1.2", d.NeighborAddress, t)
+	assert.IntEqual("neighbor as", 65001, d.NeighborAS, t)
+	assert.IntEqual("local as", 65000, d.LocalAS, t)
+	assert.StringEqual("neighbor id", "192.168.1.2", d.NeighborID, t)
+	assert.StringEqual("session", "external AS4", d.Session, t)
+	assert.StringEqual("source address", "192.168.1.1", d.SourceAddress, t)
+	assert.True("hold time", d.HoldTime == 240*time.Second, t)
+	assert.True("keepalive time", d.KeepaliveTime == 80*time.Second, t)
+	assert.IntEqual("imported", 650000, d.Routes.Imported, t)
+	assert.IntEqual("exported", 10, d.Routes.Exported, t)
+
+	_, err = s.ShowProtocolDetail("device1")
+	assert.True("error for non BGP protocol", err != nil, t)
+}
@@ -1,164 +1,955 @@
 package birdsocket
 
 import (
-        "bytes"
-        "errors"
-        "net"
-        "os"
-        "regexp"
-        "strings"
-        "time"
-        "io"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
-var birdReturnCodeRegex *regexp.Regexp
-
-func init() {
-        // Requests are commands encoded as a single line of text,
-        // replies are sequences of lines starting with a four-digit code
-        // followed by either a space (if it's the last line of the reply)
-        // or a minus sign (when the reply is going to continue with the next line),
-        // the rest of the line contains a textual message semantics of which depends
-        // on the numeric code.
-        birdReturnCodeRegex = regexp.MustCompile(`(?m)^(\d{4})`)
-}
-
-// BirdSocket encapsulates communication with Bird routing daemon
+// BirdSocket encapsulates communication with Bird routing daemon.
+// It is safe for concurrent use, queries are serialized since Bird
+// processes only one command at a time per connection
 type BirdSocket struct {
-        socketPath   string
-        bufferSize   int
-        conn         net.Conn
-        readDeadline *time.Duration
+	mu                sync.Mutex
+	socketPath        string
+	network           string
+	address           string
+	bufferSize        int
+	conn              net.Conn
+	connectTimeout    time.Duration
+	readDeadline      *time.Duration
+	writeDeadline     *time.Duration
+	maxRetries        int
+	restricted        bool
+	dialer            func(ctx context.Context) (net.Conn, error)
+	logger            Logger
+	observer          Observer
+	allowMultiline    bool
+	maxReplySize      int
+	tlsConfig         *tls.Config
+	version           string
+	keepAlive         time.Duration
+	keepAliveMu       sync.Mutex
+	keepAliveStop     chan struct{}
+	lastUsed          time.Time
+	completion        func(line []byte) (done, terminal bool)
+	totalDeadline     time.Duration
+	lastCode          int
+	stripControl      bool
+	normalizeNewlines bool
+	onConnect         []string
+	terminator        string
+	routerID          string
+	lastReads         int
+	lastBytes         int
+	rawDump           io.Writer
+	dirty             bool
+	pending           []byte
+	socketReadBuffer  int
 }
 
+const defaultBufferSize = 4096
+
 // BirdSocketOption applies options to BirdSocket
 type Option func(*BirdSocket)
 
-// WithBufferSize sets the buffer size for BirdSocket
+// WithBufferSize sets the buffer size for BirdSocket.
+// Sizes below 1 are ignored and the default buffer size is kept
 func WithBufferSize(bufferSize int) Option {
-        return func(s *BirdSocket) {
-                s.bufferSize = bufferSize
-        }
+	return func(s *BirdSocket) {
+		if bufferSize < 1 {
+			bufferSize = defaultBufferSize
+		}
+		s.bufferSize = bufferSize
+	}
 }
 
 func WithReadDeadline(readDeadline time.Duration) Option {
-        return func(s *BirdSocket) {
-                s.readDeadline = &readDeadline
-        }
+	return func(s *BirdSocket) {
+		s.readDeadline = &readDeadline
+	}
+}
+
+// WithWriteDeadline sets the maximum amount of time sending a query may take
+func WithWriteDeadline(writeDeadline time.Duration) Option {
+	return func(s *BirdSocket) {
+		s.writeDeadline = &writeDeadline
+	}
+}
+
+// WithMaxReplySize limits the size of a reply in bytes.
+// Reading a larger reply fails with ErrReplyTooLarge and closes the connection
+func WithMaxReplySize(n int) Option {
+	return func(s *BirdSocket) {
+		s.maxReplySize = n
+	}
+}
+
+// WithTotalDeadline limits the time from sending a query until its reply
+// was read completely to d, no matter how slowly the reply trickles in.
+// If a read deadline is set as well, the earlier one of both applies
+func WithTotalDeadline(d time.Duration) Option {
+	return func(s *BirdSocket) {
+		s.totalDeadline = d
+	}
+}
+
+// WithConnectTimeout sets the maximum amount of time to wait for the connection to be established
+func WithConnectTimeout(timeout time.Duration) Option {
+	return func(s *BirdSocket) {
+		s.connectTimeout = timeout
+	}
+}
+
+// WithAutoReconnect enables reconnecting to Bird when a query fails due to a broken connection.
+// The query is retried up to maxRetries times. Only `show` queries are retried
+// to make sure a command changing the state of Bird is never executed twice
+func WithAutoReconnect(maxRetries int) Option {
+	return func(s *BirdSocket) {
+		s.maxRetries = maxRetries
+	}
+}
+
+// WithTLS enables TLS for TCP connections (see WithNetwork), e.g. when Bird is exposed via stunnel.
+// If cfg.ServerName is empty, the host of the address is used to verify the certificate
+func WithTLS(cfg *tls.Config) Option {
+	return func(s *BirdSocket) {
+		s.tlsConfig = cfg
+	}
+}
+
+// WithRestricted restricts the session to commands not changing the state of Bird.
+// Connect fails if Bird does not acknowledge the restriction
+func WithRestricted() Option {
+	return func(s *BirdSocket) {
+		s.restricted = true
+	}
+}
+
+// WithOnConnect sets commands sent in order right after the welcome message
+// was received (and the session was restricted, see WithRestricted), e.g. `timeout 0`.
+// Connect fails if Bird replies to any of them with an error
+func WithOnConnect(cmds ...string) Option {
+	return func(s *BirdSocket) {
+		s.onConnect = cmds
+	}
+}
+
+// WithCommandTerminator sets the string appended to every query after removing
+// leading and trailing newlines, "\n" by default
+func WithCommandTerminator(terminator string) Option {
+	return func(s *BirdSocket) {
+		s.terminator = terminator
+	}
+}
+
+// WithSocketReadBuffer sets the size of the receive buffer of the operating system (SO_RCVBUF)
+// for the connection, e.g. to speed up reading large route dumps. The operating system may
+// adjust the size. If setting it fails, the connection is used anyway and the failure is logged
+func WithSocketReadBuffer(bytes int) Option {
+	return func(s *BirdSocket) {
+		s.socketReadBuffer = bytes
+	}
+}
+
+// WithAllowMultiline allows queries containing interior newlines.
+// Bird executes every line as a separate command, so queries
+// built from user input must never be sent with this option set
+func WithAllowMultiline() Option {
+	return func(s *BirdSocket) {
+		s.allowMultiline = true
+	}
+}
+
+// WithNetwork sets the network and address to connect to instead of the unix socket path,
+// e.g. "tcp" and "host:port" for Bird instances exposed via a TCP proxy
+func WithNetwork(network, address string) Option {
+	return func(s *BirdSocket) {
+		s.network = network
+		s.address = address
+	}
+}
+
+// WithDialer sets the function used to establish the connection instead of dialing the socket path.
+// This allows tests to inject an in-memory connection, e.g. one end of a net.Pipe
+// with a fake Bird serving the other end
+func WithDialer(dial func(ctx context.Context) (net.Conn, error)) Option {
+	return func(s *BirdSocket) {
+		s.dialer = dial
+	}
+}
+
+// WithNormalizeNewlines sets whether lines terminated by CRLF (e.g. by proxies translating newlines)
+// are converted to lines terminated by LF before being parsed, which is enabled by default.
+// Carriage returns not followed by a newline are kept, since they may be part of descriptions
+func WithNormalizeNewlines(normalize bool) Option {
+	return func(s *BirdSocket) {
+		s.normalizeNewlines = normalize
+	}
+}
+
+// WithStripControlChars removes ANSI escape sequences and control characters
+// other than newlines and tabs from replies
+func WithStripControlChars() Option {
+	return func(s *BirdSocket) {
+		s.stripControl = true
+	}
+}
+
+// WithCompletionFunc replaces the detection of the end of a reply, e.g. for daemons
+// framing their replies differently than Bird. fn is called for every line received,
+// including the welcome message, without the newline and returns done if the line completes the reply.
+// terminal reports whether the completing line is part of the reply,
+// otherwise it is considered a sentinel and dropped
+func WithCompletionFunc(fn func(line []byte) (done, terminal bool)) Option {
+	return func(s *BirdSocket) {
+		s.completion = fn
+	}
 }
 
 // NewSocket creates a new socket
 func NewSocket(socketPath string, opts ...Option) *BirdSocket {
-        socket := &BirdSocket{socketPath: socketPath, bufferSize: 4096}
+	socket := &BirdSocket{socketPath: socketPath, bufferSize: defaultBufferSize, lastCode: -1, normalizeNewlines: true, terminator: "\n"}
 
-        for _, o := range opts {
-                o(socket)
-        }
+	for _, o := range opts {
+		o(socket)
+	}
 
-        return socket
+	return socket
 }
 
 // Query sends an ad hoc query to Bird and waits for the reply
 func Query(socketPath, qry string, confirm bool) ([]byte, error) {
-        s := NewSocket(socketPath)
-        _, err := s.Connect(confirm)
-        if err != nil {
-                return nil, err
-        }
-        defer s.Close()
+	s := NewSocket(socketPath)
+	_, err := s.Connect(confirm)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	return s.Query(qry, confirm)
+}
 
-        return s.Query(qry, confirm)
+// QueryWithOptions sends an ad hoc query to Bird using a socket configured by opts,
+// e.g. WithReadDeadline to make sure the query does not hang forever
+func QueryWithOptions(socketPath, qry string, opts ...Option) ([]byte, error) {
+	s := NewSocket(socketPath, opts...)
+	_, err := s.Connect(true)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	return s.Query(qry, true)
 }
 
-// Connect connects to the Bird unix socket
+// ConnectAndQuery sends an ad hoc query to Bird and returns the welcome banner along with the reply
+func ConnectAndQuery(socketPath, qry string) (welcome []byte, reply []byte, err error) {
+	s := NewSocket(socketPath)
+	welcome, err = s.Connect(true)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer s.Close()
+
+	reply, err = s.Query(qry, true)
+	return welcome, reply, err
+}
+
+// ConnectAndQueryContext is like ConnectAndQuery, but ctx bounds the whole operation
+// from dialing and reading the welcome message to sending the query and reading its reply.
+// The connection is closed and ctx.Err() is returned when ctx is done before
+func ConnectAndQueryContext(ctx context.Context, socketPath, qry string) (welcome []byte, reply []byte, err error) {
+	s := NewSocket(socketPath)
+	welcome, err = s.ConnectContext(ctx, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer s.Close()
+
+	reply, err = s.QueryContext(ctx, qry, true)
+	return welcome, reply, err
+}
+
+// Connect connects to the Bird socket.
+// The welcome message is always read, but only returned if confirm is true.
+// If the read deadline (or connect timeout) is exceeded before the welcome message
+// was received completely, the bytes received so far are returned together
+// with an error matching ErrIncompleteBanner
 func (s *BirdSocket) Connect(confirm bool) ([]byte, error) {
-        var err error
-        s.conn, err = net.Dial("unix", s.socketPath)
-        if err != nil {
-                return nil, err
-        }
-
-        if confirm {
-                buf := make([]byte, s.bufferSize)
-                n, err := s.conn.Read(buf[:])
-                if err != nil {
-                        return nil, err
-                }
-                return buf[:n], err
-        }
-        return nil, err
-}
-
-// Close closes the connection to the socket
+	return s.ConnectContext(context.Background(), confirm)
+}
+
+// ConnectContext connects to the Bird socket.
+// The connection is closed and ctx.Err() is returned when ctx is done
+// before the welcome message was received
+func (s *BirdSocket) ConnectContext(ctx context.Context, confirm bool) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	welcome, err := s.connect(ctx, confirm)
+	if err != nil {
+		return welcome, err
+	}
+
+	s.startKeepAlive()
+	return welcome, nil
+}
+
+// Reconnect closes the connection (if any) and connects to the Bird socket again,
+// keeping all options. The welcome message received is returned
+func (s *BirdSocket) Reconnect() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closeConn()
+
+	welcome, err := s.connect(context.Background(), true)
+	if err != nil {
+		return welcome, err
+	}
+
+	s.startKeepAlive()
+	return welcome, nil
+}
+
+// ConnectWithRetry connects to the Bird socket like ConnectContext, retrying up to attempts times
+// while the socket does not exist or refuses connections (e.g. while Bird is starting).
+// The delay between attempts starts at backoff and doubles after every attempt.
+// Other errors are returned immediately, as is ctx.Err() when ctx is done while waiting.
+// At least one attempt is made, even if attempts is below 1
+func (s *BirdSocket) ConnectWithRetry(ctx context.Context, attempts int, backoff time.Duration) ([]byte, error) {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			t := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return nil, ctx.Err()
+			case <-t.C:
+			}
+			backoff *= 2
+		}
+
+		var welcome []byte
+		welcome, err = s.ConnectContext(ctx, true)
+		if err == nil {
+			return welcome, nil
+		}
+
+		if !errors.Is(err, ErrSocketNotFound) && !errors.Is(err, ErrConnectionRefused) {
+			return welcome, err
+		}
+
+		if s.logger != nil {
+			s.logger.Debugf("connect attempt %d of %d failed: %v", i+1, attempts, err)
+		}
+	}
+
+	return nil, err
+}
+
+func (s *BirdSocket) connect(ctx context.Context, confirm bool) ([]byte, error) {
+	var err error
+	s.conn, err = s.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.logger != nil {
+		s.logger.Debugf("connected to %s", s.conn.RemoteAddr())
+	}
+	s.lastUsed = time.Now()
+	s.routerID = ""
+	s.dirty = false
+	s.pending = nil
+
+	// the connect timeout bounds waiting for the welcome message as well
+	readDeadline := s.readDeadline
+	if s.connectTimeout > 0 && (readDeadline == nil || s.connectTimeout < *readDeadline) {
+		s.readDeadline = &s.connectTimeout
+	}
+
+	stop := closeOnDone(ctx, s.conn)
+	welcome := make([]byte, 0)
+	err = s.readLines(s.conn, func(line []byte) error {
+		welcome = append(welcome, line...)
+		return nil
+	})
+	s.readDeadline = readDeadline
+	if stop() {
+		s.conn = nil
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		s.closeConn()
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return welcome, &wrappedError{err: ErrIncompleteBanner, cause: err}
+		}
+		return nil, err
+	}
+
+	if v := parseBanner(welcome); v != "" {
+		s.version = v
+	}
+
+	if s.restricted {
+		if err := s.restrict(ctx); err != nil {
+			s.closeConn()
+			return nil, err
+		}
+	}
+
+	for _, cmd := range s.onConnect {
+		if err := s.runOnConnect(ctx, cmd); err != nil {
+			s.closeConn()
+			return nil, err
+		}
+	}
+
+	if !confirm {
+		return nil, nil
+	}
+	return welcome, nil
+}
+
+func (s *BirdSocket) dial(ctx context.Context) (net.Conn, error) {
+	if s.dialer != nil {
+		if s.connectTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.connectTimeout)
+			defer cancel()
+		}
+
+		conn, err := s.dialer(ctx)
+		if err != nil {
+			return nil, wrapDialError(err)
+		}
+
+		s.setSocketReadBuffer(conn)
+		return conn, nil
+	}
+
+	network, address := "unix", s.socketPath
+	if s.network != "" {
+		network, address = s.network, s.address
+	}
+
+	if network == "unix" {
+		if err := checkSocketPath(address); err != nil {
+			return nil, err
+		}
+	}
+
+	d := net.Dialer{Timeout: s.connectTimeout}
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		if network == "unix" && errors.Is(err, os.ErrPermission) {
+			return nil, fmt.Errorf("permission denied on %s: %w", address, err)
+		}
+		return nil, wrapDialError(err)
+	}
+
+	s.setSocketReadBuffer(conn)
+	if s.tlsConfig != nil && strings.HasPrefix(network, "tcp") {
+		return s.handshake(ctx, conn, address)
+	}
+
+	return conn, nil
+}
+
+// setSocketReadBuffer sets the receive buffer size of conn if enabled and supported by conn
+func (s *BirdSocket) setSocketReadBuffer(conn net.Conn) {
+	if s.socketReadBuffer <= 0 {
+		return
+	}
+
+	c, ok := conn.(interface{ SetReadBuffer(bytes int) error })
+	if !ok {
+		if s.logger != nil {
+			s.logger.Debugf("unable to set socket read buffer: not supported by %T", conn)
+		}
+		return
+	}
+
+	if err := c.SetReadBuffer(s.socketReadBuffer); err != nil && s.logger != nil {
+		s.logger.Debugf("unable to set socket read buffer: %v", err)
+	}
+}
+
+// wrapDialError wraps err to match ErrSocketNotFound or ErrConnectionRefused if applicable
+func wrapDialError(err error) error {
+	switch {
+	case errors.Is(err, syscall.ENOENT):
+		return &wrappedError{err: ErrSocketNotFound, cause: err}
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return &wrappedError{err: ErrConnectionRefused, cause: err}
+	default:
+		return err
+	}
+}
+
+// checkSocketPath returns a descriptive error if path does not exist or is not a socket.
+// Sockets in the abstract namespace (starting with @ or a null byte) are not checked
+func checkSocketPath(path string) error {
+	if path == "" || path[0] == '@' || path[0] == 0 {
+		return nil
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		switch {
+		case os.IsNotExist(err):
+			return &wrappedError{err: ErrSocketNotFound, cause: err}
+		case os.IsPermission(err):
+			return fmt.Errorf("permission denied on %s: %w", path, err)
+		default:
+			return err
+		}
+	}
+
+	switch mode := fi.Mode(); {
+	case mode&os.ModeSocket != 0:
+		return nil
+	case mode.IsRegular():
+		return fmt.Errorf("socket path %s is a regular file", path)
+	case mode.IsDir():
+		return fmt.Errorf("socket path %s is a directory", path)
+	default:
+		return fmt.Errorf("socket path %s is not a socket", path)
+	}
+}
+
+// handshake establishes a TLS session on conn
+func (s *BirdSocket) handshake(ctx context.Context, conn net.Conn, address string) (net.Conn, error) {
+	cfg := s.tlsConfig
+	if cfg.ServerName == "" {
+		cfg = cfg.Clone()
+		cfg.ServerName, _, _ = net.SplitHostPort(address)
+	}
+
+	if s.connectTimeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(s.connectTimeout)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	tlsConn := tls.Client(conn, cfg)
+	stop := closeOnDone(ctx, conn)
+	err := tlsConn.Handshake()
+	if stop() {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// restrict restricts the session to commands not changing the state of Bird
+func (s *BirdSocket) restrict(ctx context.Context) error {
+	b, err := s.queryContext(ctx, "restrict")
+	if err != nil {
+		return err
+	}
+
+	r := ParseReply(b)
+	if err := replyError(r); err != nil {
+		return err
+	}
+
+	if t := r.terminal(); t == nil || t.Code != CodeAccessRestricted {
+		return fmt.Errorf("unexpected reply to restrict: %q", b)
+	}
+
+	return nil
+}
+
+// runOnConnect sends cmd and returns an error if Bird replies with an error code
+func (s *BirdSocket) runOnConnect(ctx context.Context, cmd string) error {
+	b, err := s.queryContext(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	return replyError(ParseReply(b))
+}
+
+// SetBufferSize sets the buffer size used for reading subsequent replies,
+// e.g. to increase it before a large `show route`. It waits for a pending query to complete.
+// Sizes below 1 are ignored and the default buffer size is used
+func (s *BirdSocket) SetBufferSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	WithBufferSize(n)(s)
+}
+
+// LastCode returns the code of the final line of the most recent reply
+// (e.g. 0 for success, 1 for the welcome message or 8xxx and 9xxx for errors).
+// If no complete reply was received, -1 is returned
+func (s *BirdSocket) LastCode() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lastCode
+}
+
+// IsConnected returns true if a connection was established by Connect.
+// A connection closed by Bird is only detected by the next query
+func (s *BirdSocket) IsConnected() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.conn != nil
+}
+
+// Conn returns the underlying connection or nil if not connected.
+// It is meant for tuning socket options only: reading from or writing to
+// the connection directly breaks the framing of queries and replies.
+// The connection is still owned by BirdSocket and must be closed using Close
+func (s *BirdSocket) Conn() net.Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.conn
+}
+
+// Close closes the connection to the socket. It waits for a pending query
+// to complete and is safe to call multiple times. Querying after Close
+// returns ErrNotConnected until the socket is connected again
 func (s *BirdSocket) Close() {
-        if s.conn != nil {
-                s.conn.Close()
-        }
+	s.stopKeepAlive()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closeConn()
+}
+
+// closeConn closes the connection (if any), so it is reported as not connected afterwards
+func (s *BirdSocket) closeConn() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	s.pending = nil
 }
 
-// Query sends an query to Bird and waits for the reply
+// Query sends an query to Bird and waits for the reply.
+// Leading and trailing newlines are removed from qry. Queries containing interior
+// newlines are rejected with ErrMultilineQuery, since every line would be executed
+// as a separate command (e.g. when qry is built from user input).
+// If the read deadline is exceeded before the reply was complete, the lines
+// received so far are returned together with an error matching ErrDeadlineExceeded.
+// The reply is always read up to its last line, confirm is kept for compatibility
 func (s *BirdSocket) Query(qry string, confirm bool) ([]byte, error) {
-        _, err := s.conn.Write([]byte(strings.Trim(qry, "\n") + "\n"))
-        if err != nil {
-                return nil, err
-        }
-
-        output, err := s.readFromSocket(s.conn, confirm)
-        if err != nil {
-                return nil, err
-        }
-
-        return output, nil
-}
-
-func (s *BirdSocket) readFromSocket(conn net.Conn, confirm bool) ([]byte, error) {
-        b := make([]byte, 0)
-        buf := make([]byte, s.bufferSize)
-        if s.readDeadline != nil {
-                if err := s.conn.SetReadDeadline(time.Now().Add(*s.readDeadline)); err != nil {
-                        return nil, err
-                }
-        }
-
-        if confirm {
-                done := false
-                for !done {
-                        n, err := conn.Read(buf[:])
-                        if err != nil {
-                                if errors.Is(err, os.ErrDeadlineExceeded) {
-                                        break
-                                }
-                                return nil, err
-                        }
-
-                        b = append(b, buf[:n]...)
-                        done = containsActionCompletedCode(b)
-                }
-        } else {
-                for {
-                        n, err := conn.Read(buf[:])
-                        if err != nil {
-                                if err == io.EOF {
-                                        break
-                                }
-                                return nil, err
-                        }
-
-                        b = append(b, buf[:n]...)
-                }
-        }
-        return b, nil
+	return s.QueryContext(context.Background(), qry, confirm)
+}
+
+// QueryContext sends an query to Bird and waits for the reply.
+// Concurrent queries are serialized, each one holding the connection
+// until its reply was read completely.
+// The connection is closed and ctx.Err() is returned when ctx is done
+// before the reply was received completely. The read deadline
+// still applies as an upper bound for the whole query
+func (s *BirdSocket) QueryContext(ctx context.Context, qry string, confirm bool) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := time.Now()
+	output, err := s.queryContext(ctx, qry)
+	for retries := 0; retries < s.maxRetries && isConnectionError(err) && isIdempotent(qry); retries++ {
+		s.closeConn()
+		if _, err = s.connect(ctx, true); err != nil {
+			continue
+		}
+
+		output, err = s.queryContext(ctx, qry)
+	}
+
+	s.observe(qry, start, output, err)
+	return output, err
+}
+
+// QueryWithDeadline sends an query to Bird and waits for the reply
+// using the read deadline d instead of the one set for the socket.
+// A deadline of 0 waits for the reply without any read deadline
+// (the total deadline set by WithTotalDeadline still applies)
+func (s *BirdSocket) QueryWithDeadline(qry string, d time.Duration) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	defaultDeadline := s.readDeadline
+	s.readDeadline = &d
+	if d <= 0 {
+		s.readDeadline = nil
+	}
+	defer func() {
+		s.readDeadline = defaultDeadline
+	}()
+
+	start := time.Now()
+	output, err := s.queryContext(context.Background(), qry)
+	s.observe(qry, start, output, err)
+
+	return output, err
+}
+
+// QueryStats describes how a reply was read, e.g. for tuning the buffer size
+type QueryStats struct {
+	// Bytes is the number of bytes read
+	Bytes int
+
+	// Reads is the number of reads from the connection
+	Reads int
+
+	// Duration is the time from sending the query until the reply was read
+	Duration time.Duration
+
+	// Code is the code of the final line of the reply or -1 if the reply is incomplete
+	Code int
+}
+
+// QueryVerbose sends an query to Bird and waits for the reply like Query,
+// returning statistics about reading the reply along with it
+func (s *BirdSocket) QueryVerbose(qry string) ([]byte, QueryStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastReads, s.lastBytes = 0, 0
+	start := time.Now()
+	output, err := s.queryContext(context.Background(), qry)
+	stats := QueryStats{Bytes: s.lastBytes, Reads: s.lastReads, Duration: time.Since(start), Code: s.lastCode}
+	s.observe(qry, start, output, err)
+
+	return output, stats, err
+}
+
+// Ping checks that Bird answers queries by sending `show status`.
+// An error is returned if no complete reply was received within the read deadline
+func (s *BirdSocket) Ping() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ping()
+}
+
+func (s *BirdSocket) ping() error {
+	if err := s.send("show status"); err != nil {
+		return err
+	}
+
+	return s.readLines(s.conn, func(line []byte) error {
+		return nil
+	})
+}
+
+func (s *BirdSocket) queryContext(ctx context.Context, qry string) ([]byte, error) {
+	if s.conn == nil {
+		return nil, ErrNotConnected
+	}
+
+	stop := closeOnDone(ctx, s.conn)
+
+	output, err := s.query(qry)
+	if stop() {
+		s.conn = nil
+		return nil, ctx.Err()
+	}
+
+	return output, err
+}
+
+func (s *BirdSocket) query(qry string) ([]byte, error) {
+	if err := s.send(qry); err != nil {
+		return nil, err
+	}
+
+	return s.readFromSocket(s.conn)
+}
+
+// send writes the queries to the socket at once, each one followed by the command terminator
+func (s *BirdSocket) send(qrys ...string) error {
+	if s.conn == nil {
+		return ErrNotConnected
+	}
+
+	if s.dirty {
+		if err := s.recoverConn(); err != nil {
+			return err
+		}
+	}
+
+	var b strings.Builder
+	for _, qry := range qrys {
+		qry = strings.Trim(qry, "\n")
+		if !s.allowMultiline && strings.ContainsAny(qry, "\r\n") {
+			return ErrMultilineQuery
+		}
+
+		if s.logger != nil {
+			s.logger.Debugf("sending query %q", qry)
+		}
+
+		b.WriteString(qry + s.terminator)
+	}
+
+	if s.writeDeadline != nil {
+		if err := s.conn.SetWriteDeadline(time.Now().Add(*s.writeDeadline)); err != nil {
+			return err
+		}
+	}
+
+	s.lastUsed = time.Now()
+	s.dump(">>>", []byte(b.String()))
+	_, err := s.conn.Write([]byte(b.String()))
+	return err
+}
+
+// isConnectionError reports whether err indicates a broken or refused connection
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, os.ErrNotExist) {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// isIdempotent reports whether qry can safely be sent again.
+// Only `show` commands are considered idempotent since a mutating
+// command may already have been executed before the connection broke
+func isIdempotent(qry string) bool {
+	return strings.HasPrefix(strings.TrimSpace(qry), "show")
+}
+
+// closeOnDone closes conn as soon as ctx is done to interrupt pending I/O.
+// The returned function stops watching ctx and reports whether conn was closed
+func closeOnDone(ctx context.Context, conn net.Conn) func() bool {
+	if ctx.Done() == nil {
+		return func() bool { return false }
+	}
+
+	stop := make(chan struct{})
+	closed := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			closed <- true
+		case <-stop:
+			closed <- false
+		}
+	}()
+
+	return func() bool {
+		close(stop)
+		return <-closed
+	}
 }
 
+// readFromSocket reads the reply up to its last line. If the read deadline
+// is exceeded or the connection closed before, the partial reply is returned
+// together with an error matching ErrDeadlineExceeded or ErrIncompleteReply
+func (s *BirdSocket) readFromSocket(conn net.Conn) ([]byte, error) {
+	b := make([]byte, 0)
+	err := s.readLines(conn, func(line []byte) error {
+		b = append(b, line...)
+		return nil
+	})
+	if s.stripControl {
+		b = stripControlChars(b)
+	}
+	if err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return b, &wrappedError{err: ErrDeadlineExceeded, cause: err}
+		}
+		if err == io.EOF {
+			return b, &wrappedError{err: ErrIncompleteReply, cause: err}
+		}
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// stripControlChars removes ANSI escape sequences (like `ESC[31m`) and control characters
+// except newlines and tabs from b. Bytes of multibyte UTF-8 sequences are never control characters
+func stripControlChars(b []byte) []byte {
+	out := b[:0]
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+
+		if c == 0x1b && i+1 < len(b) && b[i+1] == '[' {
+			// skip parameters and intermediate bytes up to the final byte of the sequence
+			i += 2
+			for i < len(b) && (b[i] < 0x40 || b[i] > 0x7e) {
+				i++
+			}
+			continue
+		}
+
+		if (c < 0x20 && c != '\n' && c != '\t') || c == 0x7f {
+			continue
+		}
+
+		out = append(out, c)
+	}
+
+	return out
+}
+
+// containsActionCompletedCode reports whether b contains the last line of a reply
+// with a code starting with 0, standing for `action successfully completed' messages.
+// Continuation lines like `0001-` do not complete the reply
 func containsActionCompletedCode(b []byte) bool {
-        codes := birdReturnCodeRegex.FindAll(b, -1)
-        for _, c := range codes {
-                // Reply codes starting with 0 stand for
-                // `action successfully completed' messages
-                if bytes.HasPrefix(c, []byte("0")) {
-                        return true
-                }
-        }
-        return false
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		code, continued, ok := parseCode(line)
+		if ok && !continued && code < 1000 {
+			return true
+		}
+	}
+
+	return false
 }
 
+// containsTerminalCode reports whether b contains the last line of a reply.
+// The last line carries a code followed by a space (or nothing at all)
+// instead of a minus sign, regardless of the reply being a success or an error
+func containsTerminalCode(b []byte) bool {
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		if isTerminalLine(line) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,101 @@
+package birdsocket
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// newBlackholeListener creates a TCP listener which never accepts connections
+// and whose accept queue is already full, so that further connection attempts
+// are not answered at all
+func newBlackholeListener(t *testing.T) string {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := syscall.Bind(fd, &syscall.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}); err != nil {
+		syscall.Close(fd)
+		t.Fatal(err)
+	}
+
+	if err := syscall.Listen(fd, 0); err != nil {
+		syscall.Close(fd)
+		t.Fatal(err)
+	}
+
+	f := os.NewFile(uintptr(fd), "blackhole")
+	l, err := net.FileListener(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		l.Close()
+	})
+
+	addr := l.Addr().String()
+	for i := 0; i < 10; i++ {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			return addr
+		}
+		t.Cleanup(func() {
+			conn.Close()
+		})
+	}
+
+	t.Skip("unable to fill accept queue")
+	return ""
+}
+
+// TestConnectTimeout simulate a scenario in which
+// the peer never accepts the connection
+func TestConnectTimeout(t *testing.T) {
+	addr := newBlackholeListener(t)
+
+	s := NewSocket("", WithNetwork("tcp", addr), WithConnectTimeout(200*time.Millisecond))
+	defer s.Close()
+
+	start := time.Now()
+	_, err := s.Connect(true)
+
+	assert.True("connect failed", err != nil, t)
+	assert.True("connect returned within timeout", time.Since(start) < time.Second, t)
+}
+
+// TestSocketReadBuffer verifies that the receive buffer of the connection is resized
+func TestSocketReadBuffer(t *testing.T) {
+	f := newFakeBird(t, serveReplies(map[string]string{}))
+
+	s := NewSocket(f.path, WithSocketReadBuffer(64*1024))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	raw, err := s.Conn().(*net.UnixConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var size int
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		size, sockErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sockErr != nil {
+		t.Fatal(sockErr)
+	}
+
+	// Linux doubles the size to allow for bookkeeping overhead
+	assert.IntEqual("read buffer", 2*64*1024, size, t)
+}
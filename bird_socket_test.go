@@ -1,7 +1,21 @@
 package birdsocket
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/czerwonk/testutils/assert"
 )
@@ -92,6 +106,17 @@ func TestIncompleteBirdShowStatus(t *testing.T) {
 	assert.False("'show status' successfully completed", completed, t)
 }
 
+// TestRouteDataStartingWithDigits simulate a scenario in which
+// indented route data starts with four digits which must not
+// be taken for the 'action successfully completed' code
+func TestRouteDataStartingWithDigits(t *testing.T) {
+	out := "1007-1000::/3              unreachable [static1 2021-02-28] * (200)\n" +
+		" 0000::/8              unreachable [static1 2021-02-28] * (200)\n"
+	completed := containsActionCompletedCode([]byte(out))
+
+	assert.False("route data completed reply", completed, t)
+}
+
 // TestTruncatedBirdShowStatus simulate a scenario in which
 // the 'action successfully completed' Bird response code
 // is present in the output of the 'show status' command
@@ -108,3 +133,1168 @@ func TestTruncatedBirdShowStatus(t *testing.T) {
 
 	assert.True("'show status' successfully completed", completed, t)
 }
+
+// TestQueryWithoutConfirmReturnsOnErrorReply simulate a scenario in which
+// a query not requiring confirmation is answered with an error reply
+// and the read must return without waiting for the read deadline
+func TestQueryWithoutConfirmReturnsOnErrorReply(t *testing.T) {
+	f := newFakeBird(t, serveReplies(map[string]string{}))
+
+	s := NewSocket(f.path, WithReadDeadline(5*time.Second))
+	_, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	start := time.Now()
+	out, err := s.Query("show foo", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("reply", "9001 syntax error\n", string(out), t)
+	assert.True("query returned before deadline", time.Since(start) < time.Second, t)
+}
+
+// TestConnectWithoutConfirm verifies that the welcome message is consumed
+// on connect without confirmation, so it is not returned as reply to the first query
+func TestConnectWithoutConfirm(t *testing.T) {
+	f := newFakeBird(t, serveReplies(map[string]string{
+		"show status": "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+	}))
+
+	s := NewSocket(f.path)
+	welcome, err := s.Connect(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	assert.IntEqual("welcome", 0, len(welcome), t)
+
+	out, err := s.Query("show status", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("reply", "1000-BIRD 1.6.4\n0013 Daemon is up and running\n", string(out), t)
+
+	out, err = Query(f.path, "show status", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("ad hoc reply", "1000-BIRD 1.6.4\n0013 Daemon is up and running\n", string(out), t)
+}
+
+// TestTerminalCodes verifies that replies ending with success
+// as well as error codes are considered complete
+func TestTerminalCodes(t *testing.T) {
+	tests := map[string]string{
+		"0000": "1002-device1  Device   master   up     2018-12-21 12:35:11\n0000 \n",
+		"8001": "8001 Network not in table\n",
+		"9001": "9001 syntax error, unexpected CF_SYM_UNDEFINED\n",
+	}
+
+	for code, out := range tests {
+		assert.True("'"+code+"' is terminal", containsTerminalCode([]byte(out)), t)
+	}
+}
+
+// TestContinuationIsNotTerminal verifies that a reply consisting
+// of continuation lines only is not considered complete
+func TestContinuationIsNotTerminal(t *testing.T) {
+	out := "1002-device1  Device   master   up     2018-12-21 12:35:11\n" +
+		" direct1  Direct   master   up     2018-12-21 12:35:11\n"
+
+	assert.False("continuation is terminal", containsTerminalCode([]byte(out)), t)
+}
+
+// TestQueryContextCanceled simulate a scenario in which
+// Bird stalls in the middle of a reply and the caller
+// cancels the query
+func TestQueryContextCanceled(t *testing.T) {
+	f := newFakeBird(t, func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+		bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte("1007-10.0.0.0/8 via 192.168.1.1 on eth0 [static1 2018-12-21] * (200)\n"))
+		time.Sleep(5 * time.Second)
+	})
+
+	s := NewSocket(f.path, WithReadDeadline(5*time.Second))
+	_, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err = s.QueryContext(ctx, "show route", true)
+
+	assert.True("error is context.Canceled", err == context.Canceled, t)
+	assert.True("query returned quickly", time.Since(start) < time.Second, t)
+	assert.False("connected", s.IsConnected(), t)
+
+	_, err = s.Query("show status", true)
+	assert.True("ErrNotConnected", err == ErrNotConnected, t)
+}
+
+// TestConnectContextCanceled simulate a scenario in which
+// Bird accepts the connection but never sends the welcome message
+func TestConnectContextCanceled(t *testing.T) {
+	f := newFakeBird(t, func(conn net.Conn) {
+		time.Sleep(5 * time.Second)
+	})
+
+	s := NewSocket(f.path)
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := s.ConnectContext(ctx, true)
+
+	assert.True("error is context.DeadlineExceeded", err == context.DeadlineExceeded, t)
+	assert.True("connect returned quickly", time.Since(start) < time.Second, t)
+	assert.False("connected", s.IsConnected(), t)
+}
+
+// TestTCPConnection verifies that Bird can be queried
+// via a TCP connection
+func TestTCPConnection(t *testing.T) {
+	f := newFakeTCPBird(t, serveReplies(map[string]string{
+		"show status": "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+	}))
+
+	s := NewSocket("", WithNetwork("tcp", f.path))
+	welcome, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	assert.StringEqual("welcome", fakeWelcome, string(welcome), t)
+
+	out, err := s.Query("show status", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("reply", "1000-BIRD 1.6.4\n0013 Daemon is up and running\n", string(out), t)
+}
+
+// TestConcurrentQueries verifies that concurrent queries on
+// the same socket do not interleave their requests and replies
+func TestConcurrentQueries(t *testing.T) {
+	f := newFakeBird(t, func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+
+		r := bufio.NewReader(conn)
+		for {
+			qry, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			conn.Write([]byte("1000-" + qry))
+			conn.Write([]byte("0000 \n"))
+		}
+	})
+
+	s := NewSocket(f.path, WithReadDeadline(5*time.Second))
+	_, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			qry := fmt.Sprintf("echo %d", i)
+			out, err := s.Query(qry, true)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			if expected := "1000-" + qry + "\n0000 \n"; string(out) != expected {
+				errs <- fmt.Errorf("expected %q but got %q", expected, out)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// TestAutoReconnect simulate a scenario in which Bird closes
+// the connection between two queries
+func TestAutoReconnect(t *testing.T) {
+	f := newFakeBird(t, func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+		bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte("0013 Daemon is up and running\n"))
+	})
+
+	s := NewSocket(f.path, WithReadDeadline(5*time.Second), WithAutoReconnect(1))
+	_, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 2; i++ {
+		out, err := s.Query("show status", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.StringEqual(fmt.Sprintf("reply %d", i), "0013 Daemon is up and running\n", string(out), t)
+	}
+}
+
+// TestAutoReconnectSkipsMutatingQueries verifies that queries
+// changing the state of Bird are not retried after a reconnect
+func TestAutoReconnectSkipsMutatingQueries(t *testing.T) {
+	f := newFakeBird(t, func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+		bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte("0003 Reconfigured\n"))
+	})
+
+	s := NewSocket(f.path, WithReadDeadline(5*time.Second), WithAutoReconnect(1))
+	_, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	_, err = s.Query("configure", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.Query("configure", true)
+	assert.True("second query failed", err != nil, t)
+}
+
+// TestWriteDeadline simulate a scenario in which
+// Bird is wedged and does not read queries anymore
+func TestWriteDeadline(t *testing.T) {
+	f := newFakeBird(t, func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+		time.Sleep(5 * time.Second)
+	})
+
+	s := NewSocket(f.path, WithWriteDeadline(100*time.Millisecond))
+	_, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	_, err = s.Query("show route "+strings.Repeat("x", 16*1024*1024), true)
+	assert.True("deadline exceeded", errors.Is(err, os.ErrDeadlineExceeded), t)
+}
+
+// TestRestricted verifies that the session is restricted
+// right after connecting
+func TestRestricted(t *testing.T) {
+	f := newFakeBird(t, serveReplies(map[string]string{
+		"restrict": "0016 Access restricted\n",
+	}))
+
+	s := NewSocket(f.path, WithRestricted())
+	welcome, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	assert.StringEqual("welcome", fakeWelcome, string(welcome), t)
+}
+
+// TestRestrictedRejected simulate a scenario in which
+// Bird does not support restricting the session
+func TestRestrictedRejected(t *testing.T) {
+	f := newFakeBird(t, serveReplies(map[string]string{}))
+
+	s := NewSocket(f.path, WithRestricted())
+	defer s.Close()
+
+	_, err := s.Connect(true)
+
+	_, ok := err.(*BirdError)
+	assert.True("*BirdError returned", ok, t)
+	assert.False("connected", s.IsConnected(), t)
+}
+
+// TestConnectWelcomeInChunks simulate a scenario in which
+// the welcome message does not arrive in a single read
+func TestConnectWelcomeInChunks(t *testing.T) {
+	f := newFakeBird(t, func(conn net.Conn) {
+		conn.Write([]byte("0001 BIRD 2.0"))
+		time.Sleep(50 * time.Millisecond)
+		conn.Write([]byte(".7 ready.\n"))
+		time.Sleep(time.Second)
+	})
+
+	s := NewSocket(f.path, WithReadDeadline(5*time.Second))
+	welcome, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	assert.StringEqual("welcome", "0001 BIRD 2.0.7 ready.\n", string(welcome), t)
+}
+
+// TestConnectIncompleteBanner simulate a scenario in which
+// Bird stalls while sending the welcome message
+func TestConnectIncompleteBanner(t *testing.T) {
+	f := newFakeBird(t, func(conn net.Conn) {
+		conn.Write([]byte("0001 BIRD 2.0"))
+		time.Sleep(time.Second)
+	})
+
+	s := NewSocket(f.path, WithReadDeadline(100*time.Millisecond))
+	defer s.Close()
+
+	welcome, err := s.Connect(true)
+	assert.True("ErrIncompleteBanner", errors.Is(err, ErrIncompleteBanner), t)
+	assert.True("deadline exceeded", errors.Is(err, os.ErrDeadlineExceeded), t)
+	assert.StringEqual("partial welcome", "0001 BIRD 2.0", string(welcome), t)
+	assert.False("connected", s.IsConnected(), t)
+
+	_, err = s.Query("show status", true)
+	assert.True("ErrNotConnected", err == ErrNotConnected, t)
+}
+
+// TestPing verifies that a healthy Bird answers a ping
+// and accepts queries afterwards
+func TestPing(t *testing.T) {
+	f := newFakeBird(t, serveReplies(map[string]string{
+		"show status": "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+	}))
+
+	s := NewSocket(f.path, WithReadDeadline(time.Second))
+	_, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := s.Query("show status", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("reply", "1000-BIRD 1.6.4\n0013 Daemon is up and running\n", string(out), t)
+}
+
+// TestPingSilent simulate a scenario in which
+// Bird accepts queries but never answers
+func TestPingSilent(t *testing.T) {
+	f := newFakeBird(t, func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+		bufio.NewReader(conn).ReadString('\n')
+		time.Sleep(time.Second)
+	})
+
+	s := NewSocket(f.path, WithReadDeadline(100*time.Millisecond))
+	_, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	err = s.Ping()
+	assert.True("deadline exceeded", errors.Is(err, os.ErrDeadlineExceeded), t)
+}
+
+// TestConn verifies that the underlying connection is
+// only returned when connected
+func TestConn(t *testing.T) {
+	f := newFakeBird(t, serveReplies(map[string]string{}))
+
+	s := NewSocket(f.path)
+	assert.True("no connection before connect", s.Conn() == nil, t)
+
+	_, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	assert.True("connection after connect", s.Conn() != nil, t)
+}
+
+// TestQueryWithDeadline simulate a scenario in which a reply
+// takes longer than the deadline of a single query while the
+// default deadline of the socket is generous
+func TestQueryWithDeadline(t *testing.T) {
+	f := newFakeBird(t, func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+
+		bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte("1000-BIRD 1.6.4\n"))
+		time.Sleep(300 * time.Millisecond)
+		conn.Write([]byte("0013 Daemon is up and running\n"))
+	})
+
+	s := NewSocket(f.path, WithReadDeadline(5*time.Second))
+	_, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	start := time.Now()
+	out, _ := s.QueryWithDeadline("show status", 100*time.Millisecond)
+
+	assert.True("query timed out", time.Since(start) < 300*time.Millisecond, t)
+	assert.False("reply complete", containsTerminalCode(out), t)
+
+	assert.True("default deadline restored", *s.readDeadline == 5*time.Second, t)
+}
+
+// TestQueryNewlines verifies that only queries without
+// interior newlines are sent to Bird
+func TestQueryNewlines(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show status": "0013 Daemon is up and running\n",
+	})
+
+	for _, qry := range []string{"show status", "show status\n"} {
+		out, err := s.Query(qry, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.StringEqual(fmt.Sprintf("reply to %q", qry), "0013 Daemon is up and running\n", string(out), t)
+	}
+
+	_, err := s.Query("show status\nconfigure", true)
+	assert.True("ErrMultilineQuery returned", err == ErrMultilineQuery, t)
+}
+
+// TestQueryAllowMultiline verifies that interior newlines are
+// sent to Bird if explicitly allowed
+func TestQueryAllowMultiline(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show status": "0013 Daemon is up and running\n",
+	}, WithAllowMultiline())
+
+	out, err := s.Query("show status\nshow status", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("reply", "0013 Daemon is up and running\n", string(out), t)
+}
+
+// TestMaxReplySize simulate a scenario in which
+// Bird sends a reply larger than allowed
+func TestMaxReplySize(t *testing.T) {
+	f := newFakeBird(t, func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+		bufio.NewReader(conn).ReadString('\n')
+
+		for i := 0; i < 1000; i++ {
+			if _, err := conn.Write([]byte(" 10.0.0.0/8 via 192.168.1.1 on eth0 [static1 2018-12-21] * (200)\n")); err != nil {
+				return
+			}
+		}
+		conn.Write([]byte("0000 \n"))
+	})
+
+	s := NewSocket(f.path, WithReadDeadline(5*time.Second), WithMaxReplySize(4096))
+	_, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	_, err = s.Query("show route", true)
+	assert.True("ErrReplyTooLarge returned", err == ErrReplyTooLarge, t)
+
+	assert.False("connected", s.IsConnected(), t)
+
+	_, err = s.Query("show route", true)
+	assert.True("ErrNotConnected", err == ErrNotConnected, t)
+}
+
+// TestEOFAfterReply simulate a scenario in which Bird closes
+// the connection right after a complete reply
+func TestEOFAfterReply(t *testing.T) {
+	f := newFakeBird(t, func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+		bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte("1000-BIRD 1.6.4\n0013 Daemon is up and running\n"))
+	})
+
+	s := NewSocket(f.path, WithReadDeadline(5*time.Second))
+	_, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	out, err := s.Query("show status", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("reply", "1000-BIRD 1.6.4\n0013 Daemon is up and running\n", string(out), t)
+}
+
+// TestEOFInMiddleOfReply simulate a scenario in which Bird closes
+// the connection before the reply is complete
+func TestEOFInMiddleOfReply(t *testing.T) {
+	f := newFakeBird(t, func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+		bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte("1007-10.0.0.0/8 via 192.168.1.1 on eth0 [static1 2018-12-21] * (200)\n 10.1.0.0/16"))
+	})
+
+	s := NewSocket(f.path, WithReadDeadline(5*time.Second))
+	_, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	out, err := s.Query("show route", true)
+
+	assert.True("ErrIncompleteReply returned", errors.Is(err, ErrIncompleteReply), t)
+	assert.StringEqual("partial reply", "1007-10.0.0.0/8 via 192.168.1.1 on eth0 [static1 2018-12-21] * (200)\n 10.1.0.0/16", string(out), t)
+}
+
+// TestTLSConnection verifies that Bird can be queried
+// via a TCP connection secured by TLS
+func TestTLSConnection(t *testing.T) {
+	f, pool := newFakeTLSBird(t, serveReplies(map[string]string{
+		"show status": "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+	}))
+
+	s := NewSocket("", WithNetwork("tcp", f.path), WithTLS(&tls.Config{RootCAs: pool}), WithReadDeadline(5*time.Second))
+	welcome, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	assert.StringEqual("welcome", fakeWelcome, string(welcome), t)
+
+	out, err := s.Query("show status", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("reply", "1000-BIRD 1.6.4\n0013 Daemon is up and running\n", string(out), t)
+}
+
+// TestTLSUntrustedCertificate verifies that Connect fails if
+// the certificate presented by the peer is not trusted
+func TestTLSUntrustedCertificate(t *testing.T) {
+	f, _ := newFakeTLSBird(t, serveReplies(map[string]string{}))
+
+	s := NewSocket("", WithNetwork("tcp", f.path), WithTLS(&tls.Config{}))
+	defer s.Close()
+
+	_, err := s.Connect(true)
+	assert.True("connect failed", err != nil, t)
+}
+
+// TestConnectAndQuery verifies that both the welcome banner and the reply are returned
+func TestConnectAndQuery(t *testing.T) {
+	f := newFakeBird(t, serveReplies(map[string]string{
+		"show status": "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+	}))
+
+	welcome, reply, err := ConnectAndQuery(f.path, "show status")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("welcome", fakeWelcome, string(welcome), t)
+	assert.StringEqual("reply", "1000-BIRD 1.6.4\n0013 Daemon is up and running\n", string(reply), t)
+}
+
+// TestConnectAndQueryContext verifies that the context bounds
+// waiting for the welcome message as well as the reply
+func TestConnectAndQueryContext(t *testing.T) {
+	f := newFakeBird(t, serveReplies(map[string]string{
+		"show status": "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+	}))
+
+	welcome, reply, err := ConnectAndQueryContext(context.Background(), f.path, "show status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("welcome", fakeWelcome, string(welcome), t)
+	assert.StringEqual("reply", "1000-BIRD 1.6.4\n0013 Daemon is up and running\n", string(reply), t)
+
+	slow := newFakeBird(t, func(conn net.Conn) {
+		time.Sleep(time.Second)
+		conn.Write([]byte(fakeWelcome))
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, _, err = ConnectAndQueryContext(ctx, slow.path, "show status")
+	assert.True("context.DeadlineExceeded", err == context.DeadlineExceeded, t)
+	assert.True("deadline applied", time.Since(start) < time.Second, t)
+}
+
+// TestSetBufferSize verifies that replies are parsed after changing the buffer size between queries
+func TestSetBufferSize(t *testing.T) {
+	reply := "1000-BIRD 1.6.4\n1011-Router ID is 192.168.1.9\n0013 Daemon is up and running\n"
+	s := connectFakeBird(t, map[string]string{
+		"show status": reply,
+	}, WithBufferSize(8))
+
+	for _, size := range []int{8, 3, 64 * 1024, 0} {
+		s.SetBufferSize(size)
+
+		out, err := s.Query("show status", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.StringEqual(fmt.Sprintf("reply with buffer size %d", size), reply, string(out), t)
+	}
+
+	assert.IntEqual("default buffer size", defaultBufferSize, s.bufferSize, t)
+}
+
+// TestConnectWithRetry verifies that connecting is retried while Bird refuses connections
+func TestConnectWithRetry(t *testing.T) {
+	var attempts int32
+	dial := pipeDialer(serveReplies(map[string]string{}))
+	s := NewSocket("", WithDialer(func(ctx context.Context) (net.Conn, error) {
+		if atomic.AddInt32(&attempts, 1) <= 2 {
+			return nil, &net.OpError{Op: "dial", Net: "unix", Err: syscall.ECONNREFUSED}
+		}
+
+		return dial(ctx)
+	}))
+
+	welcome, err := s.ConnectWithRetry(context.Background(), 5, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	assert.StringEqual("welcome", fakeWelcome, string(welcome), t)
+	assert.IntEqual("attempts", 3, int(atomic.LoadInt32(&attempts)), t)
+}
+
+// TestConnectWithRetryFailure verifies that only transient errors are retried
+// and that retrying stops when attempts are exhausted or the context is done
+func TestConnectWithRetryFailure(t *testing.T) {
+	var attempts int32
+	s := NewSocket("", WithDialer(func(ctx context.Context) (net.Conn, error) {
+		atomic.AddInt32(&attempts, 1)
+		return nil, errors.New("authentication failed")
+	}))
+
+	_, err := s.ConnectWithRetry(context.Background(), 5, 10*time.Millisecond)
+	assert.True("error returned", err != nil, t)
+	assert.IntEqual("not retried", 1, int(atomic.LoadInt32(&attempts)), t)
+
+	missing := NewSocket(filepath.Join(os.TempDir(), "bird-missing.ctl"))
+	_, err = missing.ConnectWithRetry(context.Background(), 3, 10*time.Millisecond)
+	assert.True("ErrSocketNotFound", errors.Is(err, ErrSocketNotFound), t)
+
+	_, err = missing.ConnectWithRetry(context.Background(), 0, 10*time.Millisecond)
+	assert.True("ErrSocketNotFound without attempts", errors.Is(err, ErrSocketNotFound), t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = missing.ConnectWithRetry(ctx, 10, time.Second)
+	assert.True("context.DeadlineExceeded", err == context.DeadlineExceeded, t)
+	assert.True("cancelled while waiting", time.Since(start) < time.Second, t)
+}
+
+// TestCommandTerminator verifies that queries are terminated by the custom terminator
+func TestCommandTerminator(t *testing.T) {
+	received := make(chan string, 1)
+	s := NewSocket("", WithCommandTerminator("\r\n"), WithDialer(pipeDialer(func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+
+		qry, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			return
+		}
+		received <- qry
+
+		conn.Write([]byte("0013 Daemon is up and running\n"))
+	})))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err := s.Query("show status\n", true); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("query", "show status\r\n", <-received, t)
+}
+
+// TestQueryVerbose verifies the statistics of a reply arriving in multiple chunks
+func TestQueryVerbose(t *testing.T) {
+	chunks := []string{
+		"1007-10.0.0.0/8 via 192.168.1.1 on eth0 [sta",
+		"tic1 2018-12-21] * (200)\n",
+		"0000 \n",
+	}
+	s := NewSocket("", WithDialer(pipeDialer(serveChunks(chunks...))))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	out, stats, err := s.QueryVerbose("show route")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("reply", strings.Join(chunks, ""), string(out), t)
+	assert.IntEqual("bytes", len(out), stats.Bytes, t)
+	assert.IntEqual("reads", 3, stats.Reads, t)
+	assert.IntEqual("code", 0, stats.Code, t)
+	assert.True("duration", stats.Duration >= 20*time.Millisecond, t)
+}
+
+// TestQueryWithOptions verifies that the options apply to the ad hoc query,
+// so a reply which never completes does not block forever
+func TestQueryWithOptions(t *testing.T) {
+	f := newFakeBird(t, serveReplies(map[string]string{
+		"show status":    "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+		"show protocols": "2002-name     proto    table    state  since       info\n",
+	}))
+
+	reply, err := QueryWithOptions(f.path, "show status", WithReadDeadline(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("reply", "1000-BIRD 1.6.4\n0013 Daemon is up and running\n", string(reply), t)
+
+	start := time.Now()
+	_, err = QueryWithOptions(f.path, "show protocols", WithReadDeadline(100*time.Millisecond))
+	assert.True("ErrDeadlineExceeded", errors.Is(err, ErrDeadlineExceeded), t)
+	assert.True("deadline applied", time.Since(start) < time.Second, t)
+}
+
+// TestInvalidBufferSize verifies that a buffer size of zero falls back to the default
+// instead of wedging the read loop
+func TestInvalidBufferSize(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		s := connectFakeBird(t, map[string]string{
+			"show status": "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+		}, WithBufferSize(size), WithReadDeadline(5*time.Second))
+
+		assert.IntEqual("buffer size", defaultBufferSize, s.bufferSize, t)
+
+		out, err := s.Query("show status", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.StringEqual("reply", "1000-BIRD 1.6.4\n0013 Daemon is up and running\n", string(out), t)
+	}
+}
+
+// TestDeadlineReturnsPartialReply simulate a scenario in which Bird stalls
+// in the middle of a reply and verifies that the lines received before
+// the read deadline was exceeded are returned
+func TestDeadlineReturnsPartialReply(t *testing.T) {
+	partial := "1002-device1  Device   master   up     2018-12-21 12:35:11\n" +
+		" direct1  Direct   master   up     2018-12-21 12:35:11\n"
+
+	for _, confirm := range []bool{true, false} {
+		f := newFakeBird(t, func(conn net.Conn) {
+			conn.Write([]byte(fakeWelcome))
+			bufio.NewReader(conn).ReadString('\n')
+			conn.Write([]byte(partial + " kernel1  Kern"))
+			time.Sleep(time.Second)
+		})
+
+		s := NewSocket(f.path, WithReadDeadline(100*time.Millisecond))
+		if _, err := s.Connect(true); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := s.Query("show protocols", confirm)
+		s.Close()
+
+		assert.True("ErrDeadlineExceeded", errors.Is(err, ErrDeadlineExceeded), t)
+		assert.True("os.ErrDeadlineExceeded", errors.Is(err, os.ErrDeadlineExceeded), t)
+		assert.StringEqual("partial reply", partial+" kernel1  Kern", string(out), t)
+	}
+}
+
+// TestReconnect verifies that queries succeed after reconnecting a closed socket
+// and that reconnecting a socket never connected is safe
+func TestReconnect(t *testing.T) {
+	var dials int32
+	dial := pipeDialer(serveReplies(map[string]string{
+		"show status": "0013 Daemon is up and running\n",
+	}))
+	s := NewSocket("", WithDialer(countingDialer(dial, &dials)))
+	defer s.Close()
+
+	for i := 0; i < 2; i++ {
+		welcome, err := s.Reconnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.StringEqual("welcome", fakeWelcome, string(welcome), t)
+
+		out, err := s.Query("show status", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.StringEqual("reply", "0013 Daemon is up and running\n", string(out), t)
+
+		s.Close()
+	}
+
+	assert.IntEqual("dials", 2, int(atomic.LoadInt32(&dials)), t)
+}
+
+// TestQueryNotConnected verifies that querying before Connect returns ErrNotConnected
+func TestQueryNotConnected(t *testing.T) {
+	s := NewSocket("")
+
+	_, err := s.Query("show status", true)
+	assert.True("ErrNotConnected", err == ErrNotConnected, t)
+
+	s = connectFakeBird(t, map[string]string{})
+	assert.True("connected", s.IsConnected(), t)
+}
+
+// TestQueryVariantsNotConnected verifies that all query variants return ErrNotConnected
+// instead of panicking when called before Connect
+func TestQueryVariantsNotConnected(t *testing.T) {
+	s := NewSocket("")
+
+	_, err := s.QueryContext(context.Background(), "show status", true)
+	assert.True("QueryContext", err == ErrNotConnected, t)
+
+	_, err = s.QueryWithDeadline("show status", time.Second)
+	assert.True("QueryWithDeadline", err == ErrNotConnected, t)
+
+	err = s.QueryStream("show status", func(line []byte) error {
+		return nil
+	})
+	assert.True("QueryStream", err == ErrNotConnected, t)
+
+	_, err = s.QueryParsed("show status")
+	assert.True("QueryParsed", err == ErrNotConnected, t)
+
+	assert.True("Ping", s.Ping() == ErrNotConnected, t)
+}
+
+// TestAutoReconnectFailing verifies that failing reconnects are retried
+// without panicking on the missing connection
+func TestAutoReconnectFailing(t *testing.T) {
+	var dials int32
+	dial := func(ctx context.Context) (net.Conn, error) {
+		if atomic.AddInt32(&dials, 1) == 1 {
+			return pipeDialer(func(conn net.Conn) {
+				conn.Write([]byte(fakeWelcome))
+			})(ctx)
+		}
+
+		return nil, &net.OpError{Op: "dial", Net: "pipe", Err: syscall.ECONNREFUSED}
+	}
+
+	s := NewSocket("", WithDialer(dial), WithAutoReconnect(2))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	_, err := s.Query("show status", true)
+	assert.True("error returned", err != nil, t)
+	assert.IntEqual("dials", 3, int(atomic.LoadInt32(&dials)), t)
+}
+
+// TestContinuationWithActionCompletedCode simulate a scenario in which
+// a data line of a reply begins with the code 0001 followed by a minus sign
+// and verifies that the read does not stop at this line
+func TestContinuationWithActionCompletedCode(t *testing.T) {
+	out := "0001-BIRD 1.6.4 ready.\n" +
+		"1000-BIRD 1.6.4\n"
+	assert.False("continuation completed", containsActionCompletedCode([]byte(out)), t)
+
+	reply := "0001-foo\n" +
+		"0001-bar\n" +
+		"0000 \n"
+	s := connectFakeBird(t, map[string]string{
+		"show foo": reply,
+	}, WithReadDeadline(5*time.Second))
+
+	b, err := s.Query("show foo", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("reply", reply, string(b), t)
+}
+
+// TestConnectSilentSocket simulate a scenario in which the socket accepts
+// the connection but never sends the welcome message
+func TestConnectSilentSocket(t *testing.T) {
+	f := newFakeBird(t, func(conn net.Conn) {
+		time.Sleep(5 * time.Second)
+	})
+
+	for name, opt := range map[string]Option{
+		"read deadline":   WithReadDeadline(100 * time.Millisecond),
+		"connect timeout": WithConnectTimeout(100 * time.Millisecond),
+	} {
+		s := NewSocket(f.path, opt)
+
+		start := time.Now()
+		_, err := s.Connect(true)
+		assert.True(name+": deadline exceeded", errors.Is(err, os.ErrDeadlineExceeded), t)
+		assert.True(name+": timed out", time.Since(start) < 2*time.Second, t)
+	}
+}
+
+// TestSocketPathChecks verifies the errors returned for socket paths not pointing to a socket
+func TestSocketPathChecks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bird")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "bird.conf")
+	if err := ioutil.WriteFile(file, []byte{}, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{name: "missing", path: filepath.Join(dir, "bird.ctl"), expected: "does not exist"},
+		{name: "regular file", path: file, expected: "is a regular file"},
+		{name: "directory", path: dir, expected: "is a directory"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewSocket(test.path).Connect(true)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+
+			assert.True("error "+err.Error(), strings.Contains(err.Error(), test.expected), t)
+		})
+	}
+
+	f := newFakeBird(t, serveReplies(map[string]string{}))
+	s := NewSocket(f.path)
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+}
+
+// TestCloseTwice verifies that closing a socket twice is safe
+// and querying a closed socket returns ErrNotConnected
+func TestCloseTwice(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{})
+
+	s.Close()
+	s.Close()
+
+	_, err := s.Query("show status", true)
+	assert.True("ErrNotConnected", err == ErrNotConnected, t)
+}
+
+// TestStripControlChars verifies that control characters and ANSI escape sequences
+// are removed from replies while UTF-8 is preserved
+func TestStripControlChars(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show protocols all": "1002-device1\x00  Device   master   \x1b[32mup\x1b[0m     2018-12-21\r\n" +
+			"1006-  Description:    Zürich → Genève\x07\n" +
+			"  \tPreference:     240\x7f\n" +
+			"0000 \n",
+	}, WithStripControlChars())
+
+	out, err := s.Query("show protocols all", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1002-device1  Device   master   up     2018-12-21\n" +
+		"1006-  Description:    Zürich → Genève\n" +
+		"  \tPreference:     240\n" +
+		"0000 \n"
+	assert.StringEqual("reply", expected, string(out), t)
+}
+
+// TestConnectErrors verifies the errors returned if Bird is not running
+func TestConnectErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bird")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, err = NewSocket(filepath.Join(dir, "bird.ctl")).Connect(true)
+	assert.True("ErrSocketNotFound", errors.Is(err, ErrSocketNotFound), t)
+	assert.True("os.ErrNotExist", errors.Is(err, os.ErrNotExist), t)
+
+	// a socket left behind by a Bird not running anymore
+	path := filepath.Join(dir, "stale.ctl")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.SetUnlinkOnClose(false)
+	l.Close()
+
+	_, err = NewSocket(path).Connect(true)
+	assert.True("ErrConnectionRefused", errors.Is(err, ErrConnectionRefused), t)
+	assert.True("ECONNREFUSED", errors.Is(err, syscall.ECONNREFUSED), t)
+
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := tcp.Addr().String()
+	tcp.Close()
+
+	_, err = NewSocket("", WithNetwork("tcp", addr)).Connect(true)
+	assert.True("tcp: ErrConnectionRefused", errors.Is(err, ErrConnectionRefused), t)
+}
+
+// TestOnConnect verifies that the commands are sent in order right after the welcome message
+func TestOnConnect(t *testing.T) {
+	var mu sync.Mutex
+	received := make([]string, 0)
+	f := newFakeBird(t, func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			mu.Lock()
+			received = append(received, scanner.Text())
+			mu.Unlock()
+
+			reply := "0000 \n"
+			if scanner.Text() == "restrict" {
+				reply = "0016 Access restricted\n"
+			}
+			conn.Write([]byte(reply))
+		}
+	})
+
+	s := NewSocket(f.path, WithOnConnect("restrict", "timeout 0"))
+	welcome, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	assert.StringEqual("welcome", fakeWelcome, string(welcome), t)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.StringEqual("commands", "restrict,timeout 0", strings.Join(received, ","), t)
+}
+
+// TestOnConnectFailure verifies that Connect fails if Bird rejects one of the commands
+func TestOnConnectFailure(t *testing.T) {
+	f := newFakeBird(t, serveReplies(map[string]string{
+		"restrict": "0016 Access restricted\n",
+	}))
+
+	s := NewSocket(f.path, WithOnConnect("restrict", "timeout 0", "show status"))
+	defer s.Close()
+
+	_, err := s.Connect(false)
+	birdErr, ok := err.(*BirdError)
+	assert.True("*BirdError returned", ok, t)
+	if ok {
+		assert.IntEqual("code", 9001, birdErr.Code, t)
+	}
+}
+
+// TestQueryWithoutDeadline verifies that a reply taking longer than the default
+// deadline of the socket completes when querying without a deadline
+func TestQueryWithoutDeadline(t *testing.T) {
+	f := newFakeBird(t, func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+
+		r := bufio.NewReader(conn)
+		for {
+			if _, err := r.ReadString('\n'); err != nil {
+				return
+			}
+
+			conn.Write([]byte("1000-BIRD 1.6.4\n"))
+			time.Sleep(300 * time.Millisecond)
+			conn.Write([]byte("0013 Daemon is up and running\n"))
+		}
+	})
+
+	s := NewSocket(f.path, WithReadDeadline(100*time.Millisecond))
+	_, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	out, err := s.QueryWithDeadline("show status", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True("reply complete", containsTerminalCode(out), t)
+	assert.True("default deadline restored", *s.readDeadline == 100*time.Millisecond, t)
+
+	_, err = s.Query("show status", true)
+	assert.True("default deadline exceeded", errors.Is(err, ErrDeadlineExceeded), t)
+}
+
+// TestSocketReadBufferUnsupported verifies that connections not supporting
+// setting the read buffer size are used anyway
+func TestSocketReadBufferUnsupported(t *testing.T) {
+	l := &recordingLogger{}
+	s := connectFakeBird(t, map[string]string{
+		"show status": "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+	}, WithSocketReadBuffer(1<<20), WithLogger(l))
+
+	if _, err := s.Query("show status", true); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True("failure logged", strings.HasPrefix(l.events[0], "unable to set socket read buffer"), t)
+}
@@ -0,0 +1,52 @@
+package birdsocket
+
+// Client provides typed access to commonly used Bird commands
+type Client struct {
+	socket *BirdSocket
+}
+
+// NewClient creates a client sending commands using the connected socket s
+func NewClient(s *BirdSocket) *Client {
+	return &Client{socket: s}
+}
+
+// Socket returns the socket used by the client
+func (c *Client) Socket() *BirdSocket {
+	return c.socket
+}
+
+// Status returns the status of the Bird daemon
+func (c *Client) Status() (*Status, error) {
+	return c.socket.ShowStatus()
+}
+
+// Protocols returns the list of protocol instances
+func (c *Client) Protocols() ([]Protocol, error) {
+	return c.socket.ShowProtocols()
+}
+
+// Routes returns the routes matching the options
+func (c *Client) Routes(opts ...RouteOption) ([]Route, error) {
+	return c.socket.ShowRoute(opts...)
+}
+
+// ProtocolDetail returns the protocol instance name including its details
+func (c *Client) ProtocolDetail(name string) (*Protocol, error) {
+	p, _, err := c.socket.showProtocolAll(name)
+	return p, err
+}
+
+// Enable enables the protocol instance name
+func (c *Client) Enable(name string) error {
+	return c.socket.EnableProtocol(name)
+}
+
+// Disable disables the protocol instance name
+func (c *Client) Disable(name string) error {
+	return c.socket.DisableProtocol(name)
+}
+
+// Restart restarts the protocol instance name
+func (c *Client) Restart(name string) error {
+	return c.socket.RestartProtocol(name)
+}
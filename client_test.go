@@ -0,0 +1,98 @@
+package birdsocket
+
+import (
+	"testing"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+func newFakeClient(t *testing.T) *Client {
+	return NewClient(connectFakeBird(t, map[string]string{
+		"show status": "1000-BIRD 2.0.8\n" +
+			"1011-Router ID is 10.0.0.1\n" +
+			"0013 Daemon is up and running\n",
+		"show protocols": "2002-Name       Proto      Table      State  Since         Info\n" +
+			"1002-device1    Device     ---        up     2021-02-28 09:00:00\n" +
+			" bgp1       BGP        ---        up     2021-02-28 09:00:05  Established\n" +
+			"0000 \n",
+		"show protocols all bgp1": "2002-Name       Proto      Table      State  Since         Info\n" +
+			"1002-bgp1       BGP        ---        up     2021-02-28 09:00:05  Established\n" +
+			"1006-  Description:    Upstream\n" +
+			"\n" +
+			"0000 \n",
+		"show route": "1007-Table master4:\n" +
+			" 10.0.0.0/8           unicast [static1 2021-02-28] * (200)\n" +
+			" \tvia 192.168.1.1 on eth0\n" +
+			"0000 \n",
+		"enable bgp1":  "0011-bgp1: enabled\n0000 \n",
+		"disable bgp1": "0009-bgp1: disabled\n0000 \n",
+		"restart bgp1": "0012-bgp1: restarted\n0000 \n",
+	}))
+}
+
+// TestClientStatus verifies Client.Status
+func TestClientStatus(t *testing.T) {
+	st, err := newFakeClient(t).Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("router id", "10.0.0.1", st.RouterID, t)
+}
+
+// TestClientProtocols verifies Client.Protocols
+func TestClientProtocols(t *testing.T) {
+	protocols, err := newFakeClient(t).Protocols()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("protocols", 2, len(protocols), t)
+	assert.StringEqual("name", "bgp1", protocols[1].Name, t)
+}
+
+// TestClientProtocolDetail verifies Client.ProtocolDetail
+func TestClientProtocolDetail(t *testing.T) {
+	c := newFakeClient(t)
+
+	p, err := c.ProtocolDetail("bgp1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("name", "bgp1", p.Name, t)
+	assert.StringEqual("description", "Upstream", p.Detail.Description, t)
+
+	_, err = c.ProtocolDetail("bgp2")
+	_, ok := err.(*BirdError)
+	assert.True("*BirdError returned", ok, t)
+}
+
+// TestClientRoutes verifies Client.Routes
+func TestClientRoutes(t *testing.T) {
+	routes, err := newFakeClient(t).Routes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("routes", 1, len(routes), t)
+	assert.StringEqual("gateway", "192.168.1.1", routes[0].Gateway, t)
+}
+
+// TestClientProtocolControl verifies Client.Enable, Client.Disable and Client.Restart
+func TestClientProtocolControl(t *testing.T) {
+	c := newFakeClient(t)
+
+	for name, f := range map[string]func(string) error{
+		"enable":  c.Enable,
+		"disable": c.Disable,
+		"restart": c.Restart,
+	} {
+		if err := f("bgp1"); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		_, ok := f("bgp2").(*BirdError)
+		assert.True(name+" returned *BirdError for unknown protocol", ok, t)
+	}
+}
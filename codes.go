@@ -0,0 +1,97 @@
+package birdsocket
+
+// Reply codes sent by Bird as documented in doc/reply_codes
+// of the BIRD 2.0 source tree (BIRD 1.6 uses the same codes
+// but does not send all of them)
+const (
+	// 0xxx: action successfully completed
+	CodeReplyOK                = 0
+	CodeWelcome                = 1
+	CodeReadingConfig          = 2
+	CodeReconfigured           = 3
+	CodeReconfigInProgress     = 4
+	CodeReconfigQueued         = 5
+	CodeReconfigIgnored        = 6
+	CodeShutdownOrdered        = 7
+	CodeAlreadyDisabled        = 8
+	CodeDisabled               = 9
+	CodeAlreadyEnabled         = 10
+	CodeEnabled                = 11
+	CodeRestarted              = 12
+	CodeStatusReport           = 13
+	CodeRouteCount             = 14
+	CodeReloading              = 15
+	CodeAccessRestricted       = 16
+	CodeReconfigUnqueued       = 17
+	CodeReconfigConfirmed      = 18
+	CodeNothingToDo            = 19
+	CodeConfigOK               = 20
+	CodeUndoRequested          = 21
+	CodeUndoScheduled          = 22
+	CodeEvaluation             = 23
+	CodeGracefulRestartStatus  = 24
+	CodeGracefulRestartOrdered = 25
+
+	// 1xxx: table entries
+	CodeVersion                 = 1000
+	CodeInterfaceList           = 1001
+	CodeProtocolList            = 1002
+	CodeInterfaceAddress        = 1003
+	CodeInterfaceFlags          = 1004
+	CodeInterfaceSummary        = 1005
+	CodeProtocolDetails         = 1006
+	CodeRouteList               = 1007
+	CodeRouteDetails            = 1008
+	CodeStaticRouteList         = 1009
+	CodeSymbolList              = 1010
+	CodeUptime                  = 1011
+	CodeRouteExtendedAttributes = 1012
+	CodeOSPFNeighbors           = 1013
+	CodeOSPF                    = 1014
+	CodeOSPFInterface           = 1015
+	CodeOSPFState               = 1016
+	CodeOSPFLSADB               = 1017
+	CodeMemory                  = 1018
+	CodeROAList                 = 1019
+	CodeBFDSessions             = 1020
+	CodeRIPInterfaces           = 1021
+	CodeRIPNeighbors            = 1022
+	CodeBabelInterfaces         = 1023
+	CodeBabelNeighbors          = 1024
+	CodeBabelEntries            = 1025
+	CodeBabelRoutes             = 1026
+
+	// 8xxx: run-time errors
+	CodeReplyTooLong      = 8000
+	CodeRouteNotFound     = 8001
+	CodeConfigError       = 8002
+	CodeNoProtocolsMatch  = 8003
+	CodeStoppedByReconfig = 8004
+	CodeProtocolDown      = 8005
+	CodeReloadFailed      = 8006
+	CodeAccessDenied      = 8007
+	CodeRuntimeError      = 8008
+
+	// 9xxx: parse-time errors
+	CodeCommandTooLong    = 9000
+	CodeSyntaxError       = 9001
+	CodeInvalidSymbolType = 9002
+)
+
+// IsSuccess returns true if code is sent in successful replies, i.e. for completed
+// actions (0xxx), table entries (1xxx) and table headings (2xxx)
+func IsSuccess(code int) bool {
+	return code >= 0 && code <= 2999
+}
+
+// IsError returns true if code is a run-time (8xxx) or parse-time (9xxx) error
+func IsError(code int) bool {
+	return code >= 8000 && code <= 9999
+}
+
+// IsContinuation returns true if line carries a reply code followed by a minus sign,
+// announcing that the reply continues with the next line
+func IsContinuation(line []byte) bool {
+	_, continued, ok := parseCode(line)
+	return ok && continued
+}
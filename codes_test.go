@@ -0,0 +1,64 @@
+package birdsocket
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestIsError verifies the classification of error codes
+func TestIsError(t *testing.T) {
+	tests := map[int]bool{
+		CodeReplyOK:       false,
+		CodeWelcome:       false,
+		CodeStatusReport:  false,
+		CodeProtocolList:  false,
+		CodeRouteNotFound: true,
+		CodeAccessDenied:  true,
+		CodeRuntimeError:  true,
+		CodeSyntaxError:   true,
+	}
+
+	for code, expected := range tests {
+		assert.True(fmt.Sprintf("IsError(%04d)", code), IsError(code) == expected, t)
+	}
+}
+
+// TestIsSuccess verifies the classification of codes sent in successful replies
+func TestIsSuccess(t *testing.T) {
+	tests := map[int]bool{
+		CodeReplyOK:       true,
+		CodeWelcome:       true,
+		CodeStatusReport:  true,
+		CodeProtocolList:  true,
+		CodeRouteDetails:  true,
+		2002:              true,
+		CodeReplyTooLong:  false,
+		CodeRouteNotFound: false,
+		CodeSyntaxError:   false,
+		-1:                false,
+		10000:             false,
+	}
+
+	for code, expected := range tests {
+		assert.True(fmt.Sprintf("IsSuccess(%04d)", code), IsSuccess(code) == expected, t)
+	}
+}
+
+// TestIsContinuation verifies the detection of continued lines
+func TestIsContinuation(t *testing.T) {
+	tests := map[string]bool{
+		"1002-device1  Device   master   up": true,
+		"0001-BIRD 1.6.4 ready.":             true,
+		"0000 ":                              false,
+		"0013 Daemon is up and running":      false,
+		"0000":                               false,
+		" direct1  Direct   master   up":     false,
+		"100-foo":                            false,
+	}
+
+	for line, expected := range tests {
+		assert.True(fmt.Sprintf("IsContinuation(%q)", line), IsContinuation([]byte(line)) == expected, t)
+	}
+}
@@ -0,0 +1,78 @@
+package birdsocket
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+var plainArgRegex *regexp.Regexp
+
+func init() {
+	// symbols, numbers and addresses can be passed to Bird unquoted
+	plainArgRegex = regexp.MustCompile(`^[A-Za-z0-9_.:/-]+$`)
+}
+
+// Command builds a single line command for Bird.
+// Arguments which are not plain symbols or numbers are passed as quoted strings,
+// errors are collected and returned by Build
+type Command struct {
+	parts []string
+	err   error
+}
+
+// NewCommand creates a command starting with the keywords, e.g. NewCommand("show", "route")
+func NewCommand(keywords ...string) *Command {
+	c := &Command{}
+	for _, k := range keywords {
+		c.add(k, plainArgRegex.MatchString(k), "keyword")
+	}
+
+	return c
+}
+
+// Arg appends the argument s, quoting it if it contains spaces or special characters.
+// Bird does not support escaping within strings, so arguments containing
+// double quotes or newlines are rejected
+func (c *Command) Arg(s string) *Command {
+	if plainArgRegex.MatchString(s) {
+		return c.add(s, true, "argument")
+	}
+
+	return c.add(`"`+s+`"`, s != "" && !strings.ContainsAny(s, "\"\r\n"), "argument")
+}
+
+// Prefix appends the network cidr (or IP address)
+func (c *Command) Prefix(cidr string) *Command {
+	_, _, err := net.ParseCIDR(cidr)
+	return c.add(cidr, err == nil || net.ParseIP(cidr) != nil, "prefix")
+}
+
+func (c *Command) add(s string, valid bool, kind string) *Command {
+	if !valid && c.err == nil {
+		c.err = fmt.Errorf("invalid %s: %q", kind, s)
+	}
+
+	c.parts = append(c.parts, s)
+	return c
+}
+
+// Build returns the command or the first error encountered while building it
+func (c *Command) Build() (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
+
+	if len(c.parts) == 0 {
+		return "", errors.New("empty command")
+	}
+
+	return c.String(), nil
+}
+
+// String returns the command regardless of errors encountered while building it
+func (c *Command) String() string {
+	return strings.Join(c.parts, " ")
+}
@@ -0,0 +1,58 @@
+package birdsocket
+
+import (
+	"testing"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestCommand verifies the quoting of arguments
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      *Command
+		expected string
+	}{
+		{name: "plain", cmd: NewCommand("show", "protocols").Arg("bgp_1"), expected: "show protocols bgp_1"},
+		{name: "spaces", cmd: NewCommand("show", "route", "filter").Arg("my filter"), expected: `show route filter "my filter"`},
+		{name: "special characters", cmd: NewCommand("show", "protocols").Arg("bgp*"), expected: `show protocols "bgp*"`},
+		{name: "single quote", cmd: NewCommand("echo").Arg("it's"), expected: `echo "it's"`},
+		{name: "ipv4 prefix", cmd: NewCommand("show", "route", "for").Prefix("10.0.0.0/8").Arg("all"), expected: "show route for 10.0.0.0/8 all"},
+		{name: "ipv6 prefix", cmd: NewCommand("show", "route", "for").Prefix("2001:db8::/32"), expected: "show route for 2001:db8::/32"},
+		{name: "ip address", cmd: NewCommand("show", "route", "for").Prefix("192.168.1.1"), expected: "show route for 192.168.1.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := test.cmd.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.StringEqual("command", test.expected, cmd, t)
+		})
+	}
+}
+
+// TestCommandInvalid verifies that invalid arguments are rejected
+func TestCommandInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  *Command
+	}{
+		{name: "newline", cmd: NewCommand("show", "protocols").Arg("bgp1\nconfigure")},
+		{name: "carriage return", cmd: NewCommand("show", "protocols").Arg("bgp1\r")},
+		{name: "double quote", cmd: NewCommand("show", "protocols").Arg(`bgp"1`)},
+		{name: "empty argument", cmd: NewCommand("show", "protocols").Arg("")},
+		{name: "invalid keyword", cmd: NewCommand("show protocols")},
+		{name: "invalid prefix", cmd: NewCommand("show", "route", "for").Prefix("10.0.0.0/33")},
+		{name: "empty", cmd: NewCommand()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.cmd.Build()
+			assert.True("error returned", err != nil, t)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package birdsocket
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ConfigureResult is the outcome of a successful `configure` command
+type ConfigureResult int
+
+const (
+	// Reconfigured means the new configuration is active
+	Reconfigured ConfigureResult = iota
+	// ReconfigurationInProgress means the new configuration is being applied
+	ReconfigurationInProgress
+	// ReconfigurationQueued means the new configuration is applied after the running reconfiguration
+	ReconfigurationQueued
+	// ReconfigurationUnqueued means a queued configuration was replaced
+	ReconfigurationUnqueued
+	// ReconfigurationConfirmed means a reconfiguration with timeout was confirmed
+	ReconfigurationConfirmed
+	// ReconfigurationIgnored means the reconfiguration was ignored since Bird is shutting down
+	ReconfigurationIgnored
+	// NothingToDo means there was no change to apply
+	NothingToDo
+	// ConfigurationOK means the configuration was checked successfully without applying it
+	ConfigurationOK
+)
+
+var configureResults = map[int]ConfigureResult{
+	CodeReconfigured:       Reconfigured,
+	CodeReconfigInProgress: ReconfigurationInProgress,
+	CodeReconfigQueued:     ReconfigurationQueued,
+	CodeReconfigUnqueued:   ReconfigurationUnqueued,
+	CodeReconfigConfirmed:  ReconfigurationConfirmed,
+	CodeReconfigIgnored:    ReconfigurationIgnored,
+	CodeNothingToDo:        NothingToDo,
+	CodeConfigOK:           ConfigurationOK,
+}
+
+// ConfigureOption modifies the `configure` command
+type ConfigureOption func(*configureCommand)
+
+type configureCommand struct {
+	soft    bool
+	check   bool
+	timeout int
+	file    string
+}
+
+// ConfigureSoft ignores changes in filters instead of restarting the affected protocols
+func ConfigureSoft() ConfigureOption {
+	return func(c *configureCommand) {
+		c.soft = true
+	}
+}
+
+// ConfigureCheck only checks the configuration without applying it
+func ConfigureCheck() ConfigureOption {
+	return func(c *configureCommand) {
+		c.check = true
+	}
+}
+
+// ConfigureTimeout reverts the configuration after the given number of seconds
+// unless it is confirmed by `configure confirm`
+func ConfigureTimeout(seconds int) ConfigureOption {
+	return func(c *configureCommand) {
+		c.timeout = seconds
+	}
+}
+
+// ConfigureFile reads the configuration from file instead of the default path
+func ConfigureFile(file string) ConfigureOption {
+	return func(c *configureCommand) {
+		c.file = file
+	}
+}
+
+func (c *configureCommand) String() string {
+	parts := []string{"configure"}
+	if c.check {
+		parts = append(parts, "check")
+	} else if c.soft {
+		parts = append(parts, "soft")
+	}
+
+	if c.file != "" {
+		parts = append(parts, strconv.Quote(c.file))
+	}
+
+	if c.timeout > 0 && !c.check {
+		parts = append(parts, "timeout", strconv.Itoa(c.timeout))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Configure reloads the configuration of Bird.
+// If the configuration is invalid, the error reported by Bird is returned as *BirdError.
+// ErrRestricted is returned without sending the command on restricted sessions
+func (s *BirdSocket) Configure(opts ...ConfigureOption) (ConfigureResult, error) {
+	if s.restricted {
+		return 0, ErrRestricted
+	}
+
+	c := &configureCommand{}
+	for _, o := range opts {
+		o(c)
+	}
+
+	r, err := s.QueryChecked(c.String())
+	if err != nil {
+		return 0, err
+	}
+
+	t := r.terminal()
+	if t == nil {
+		return 0, errors.New("incomplete reply to configure")
+	}
+
+	res, found := configureResults[t.Code]
+	if !found {
+		return 0, fmt.Errorf("unexpected reply to configure: %04d %s", t.Code, t.Message)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,73 @@
+package birdsocket
+
+import (
+	"testing"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestConfigure verifies the result of a successful reconfiguration
+func TestConfigure(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"configure": "0002-Reading configuration from /etc/bird.conf\n0003 Reconfigured\n",
+	})
+
+	res, err := s.Configure()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True("reconfigured", res == Reconfigured, t)
+}
+
+// TestConfigureNothingToDo verifies the result of a reconfiguration without changes
+func TestConfigureNothingToDo(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"configure soft \"/etc/bird/new.conf\" timeout 30": "0002-Reading configuration from /etc/bird/new.conf\n0019 Nothing to do\n",
+	})
+
+	res, err := s.Configure(ConfigureSoft(), ConfigureFile("/etc/bird/new.conf"), ConfigureTimeout(30))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True("nothing to do", res == NothingToDo, t)
+}
+
+// TestConfigureCheck verifies the result of a configuration check
+func TestConfigureCheck(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"configure check": "0002-Reading configuration from /etc/bird.conf\n0020 Configuration OK\n",
+	})
+
+	res, err := s.Configure(ConfigureCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True("configuration ok", res == ConfigurationOK, t)
+}
+
+// TestConfigureError verifies that a configuration error is returned as *BirdError
+func TestConfigureError(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"configure": "0002-Reading configuration from /etc/bird.conf\n8002 /etc/bird.conf:12:3 syntax error, unexpected '}'\n",
+	})
+
+	_, err := s.Configure()
+
+	birdErr, ok := err.(*BirdError)
+	assert.True("*BirdError returned", ok, t)
+	assert.IntEqual("code", CodeConfigError, birdErr.Code, t)
+	assert.StringEqual("message", "/etc/bird.conf:12:3 syntax error, unexpected '}'", birdErr.Message, t)
+}
+
+// TestConfigureRestricted verifies that configure is refused on restricted sessions
+func TestConfigureRestricted(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"restrict": "0016 Access restricted\n",
+	}, WithRestricted())
+
+	_, err := s.Configure()
+	assert.True("ErrRestricted returned", err == ErrRestricted, t)
+}
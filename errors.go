@@ -0,0 +1,104 @@
+package birdsocket
+
+import (
+	"errors"
+	"fmt"
+)
+
+// BirdError is a run-time or parse-time error reported by Bird
+type BirdError struct {
+	Code    int
+	Message string
+}
+
+func (e *BirdError) Error() string {
+	return fmt.Sprintf("%04d %s", e.Code, e.Message)
+}
+
+// Is allows matching errors reported by Bird against the sentinel errors
+// of this package using errors.Is, e.g. ErrAccessDenied
+func (e *BirdError) Is(target error) bool {
+	return target == ErrAccessDenied && e.Code == CodeAccessDenied
+}
+
+// replyError returns a *BirdError if the reply ended with an error code
+func replyError(r *Reply) error {
+	t := r.terminal()
+	if t == nil || !IsError(t.Code) {
+		return nil
+	}
+
+	return &BirdError{Code: t.Code, Message: t.Message}
+}
+
+// ErrPoolClosed is returned when querying a closed pool
+var ErrPoolClosed = errors.New("pool is closed")
+
+// ErrSocketNotFound is returned when connecting to a socket path which does not exist,
+// usually because Bird is not running
+var ErrSocketNotFound = errors.New("socket does not exist")
+
+// ErrConnectionRefused is returned when nobody is listening on the socket,
+// usually because Bird is not running
+var ErrConnectionRefused = errors.New("connection refused")
+
+// ErrNotConnected is returned when querying a socket before Connect was called
+var ErrNotConnected = errors.New("not connected")
+
+// ErrRestricted is returned when a command changing the state of Bird
+// is issued on a restricted session. It matches ErrAccessDenied as well,
+// so commands denied locally and by Bird can be detected the same way
+var ErrRestricted error = &restrictedError{}
+
+type restrictedError struct{}
+
+func (e *restrictedError) Error() string {
+	return "session is restricted to read-only commands"
+}
+
+// Is makes ErrRestricted match ErrAccessDenied using errors.Is
+func (e *restrictedError) Is(target error) bool {
+	return target == ErrAccessDenied
+}
+
+// ErrAccessDenied matches the *BirdError returned when Bird denies a command,
+// e.g. a command changing the state of Bird on a restricted session
+var ErrAccessDenied = errors.New("access denied")
+
+// ErrMultilineQuery is returned for queries containing interior newlines,
+// which would make Bird execute every line as a separate command
+var ErrMultilineQuery = errors.New("query contains interior newlines")
+
+// ErrReplyTooLarge is returned when a reply exceeds the size set by WithMaxReplySize
+var ErrReplyTooLarge = errors.New("reply exceeds maximum size")
+
+// ErrIncompleteReply is returned together with the partial reply
+// when the connection was closed before the reply was complete
+var ErrIncompleteReply = errors.New("incomplete reply")
+
+// ErrIncompleteBanner is returned together with the partial welcome message
+// when the read deadline was exceeded before the welcome message was complete
+var ErrIncompleteBanner = errors.New("incomplete welcome message")
+
+// ErrDeadlineExceeded is returned together with the partial reply
+// when the read deadline was exceeded before the reply was complete
+var ErrDeadlineExceeded = errors.New("read deadline exceeded")
+
+// wrappedError wraps the cause of an error,
+// so that both the cause and err can be matched by errors.Is
+type wrappedError struct {
+	err   error
+	cause error
+}
+
+func (e *wrappedError) Error() string {
+	return fmt.Sprintf("%v: %v", e.err, e.cause)
+}
+
+func (e *wrappedError) Is(target error) bool {
+	return target == e.err
+}
+
+func (e *wrappedError) Unwrap() error {
+	return e.cause
+}
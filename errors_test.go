@@ -0,0 +1,37 @@
+package birdsocket
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestAccessDenied verifies that commands denied by Bird can be matched against ErrAccessDenied
+func TestAccessDenied(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"configure":   "8007 Access denied\n",
+		"enable bgp1": "8007 Access denied\n",
+	})
+
+	_, err := s.Configure()
+	assert.True("configure: ErrAccessDenied", errors.Is(err, ErrAccessDenied), t)
+
+	err = s.EnableProtocol("bgp1")
+	assert.True("enable: ErrAccessDenied", errors.Is(err, ErrAccessDenied), t)
+
+	birdErr, ok := err.(*BirdError)
+	assert.True("*BirdError returned", ok, t)
+	assert.IntEqual("code", CodeAccessDenied, birdErr.Code, t)
+
+	err = s.DisableProtocol("bgp1")
+	assert.False("syntax error: ErrAccessDenied", errors.Is(err, ErrAccessDenied), t)
+}
+
+// TestRestrictedAccessDenied verifies that commands refused on restricted sessions
+// can be matched against ErrAccessDenied as well
+func TestRestrictedAccessDenied(t *testing.T) {
+	assert.True("ErrRestricted matches ErrAccessDenied", errors.Is(ErrRestricted, ErrAccessDenied), t)
+	assert.True("ErrRestricted matches itself", errors.Is(ErrRestricted, ErrRestricted), t)
+	assert.False("ErrAccessDenied does not match ErrRestricted", errors.Is(ErrAccessDenied, ErrRestricted), t)
+}
@@ -0,0 +1,32 @@
+package birdsocket
+
+import (
+	"errors"
+	"strings"
+)
+
+// Eval evaluates the filter expression expr (e.g. `1 + 2`) and returns the result.
+// Invalid expressions are reported by Bird and returned as *BirdError
+func (s *BirdSocket) Eval(expr string) (string, error) {
+	if strings.ContainsAny(expr, "\r\n") {
+		return "", ErrMultilineQuery
+	}
+
+	r, err := s.QueryChecked("eval " + expr)
+	if err != nil {
+		return "", err
+	}
+
+	result := make([]string, 0)
+	for _, l := range r.Lines {
+		if l.Code == CodeEvaluation {
+			result = append(result, l.Message)
+		}
+	}
+
+	if len(result) == 0 {
+		return "", errors.New("unexpected reply to eval")
+	}
+
+	return strings.Join(result, "\n"), nil
+}
@@ -0,0 +1,36 @@
+package birdsocket
+
+import (
+	"testing"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestEval verifies the evaluation of valid and invalid expressions
+func TestEval(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"eval 1 + 2":           "0023 3\n",
+		`eval "foo" + 1`:       "8008 runtime error\n",
+		"eval [1, 2] ~ [1..3]": "0023 TRUE\n",
+	})
+
+	res, err := s.Eval("1 + 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("result", "3", res, t)
+
+	res, err = s.Eval("[1, 2] ~ [1..3]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("result", "TRUE", res, t)
+
+	_, err = s.Eval(`"foo" + 1`)
+	birdErr, ok := err.(*BirdError)
+	assert.True("*BirdError returned", ok, t)
+	assert.IntEqual("code", CodeRuntimeError, birdErr.Code, t)
+
+	_, err = s.Eval("1 +\n")
+	assert.True("ErrMultilineQuery", err == ErrMultilineQuery, t)
+}
@@ -0,0 +1,179 @@
+package birdsocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fakeWelcome = "0001 BIRD 1.6.4 ready.\n"
+
+// fakeBird is a minimal stand-in for the Bird control socket
+type fakeBird struct {
+	// path is the unix socket path or the TCP address of the listener
+	path     string
+	listener net.Listener
+}
+
+// newFakeBird starts a fake Bird unix socket which calls handle
+// for every accepted connection
+func newFakeBird(t *testing.T, handle func(conn net.Conn)) *fakeBird {
+	dir, err := ioutil.TempDir("", "bird")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	path := filepath.Join(dir, "bird.ctl")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return serveFakeBird(t, l, handle)
+}
+
+// newFakeTCPBird starts a fake Bird socket on a local TCP port
+// which calls handle for every accepted connection
+func newFakeTCPBird(t *testing.T, handle func(conn net.Conn)) *fakeBird {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return serveFakeBird(t, l, handle)
+}
+
+// newFakeTLSBird starts a fake Bird socket on a local TCP port secured by TLS
+// using a self-signed certificate. It returns the fake and a pool trusting the certificate
+func newFakeTLSBird(t *testing.T, handle func(conn net.Conn)) (*fakeBird, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "bird"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return serveFakeBird(t, l, handle), pool
+}
+
+func serveFakeBird(t *testing.T, l net.Listener, handle func(conn net.Conn)) *fakeBird {
+	f := &fakeBird{path: l.Addr().String(), listener: l}
+	t.Cleanup(func() {
+		l.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+
+	return f
+}
+
+// serveReplies returns a handler writing the welcome banner and
+// answering each received command with the registered reply
+func serveReplies(replies map[string]string) func(conn net.Conn) {
+	return serveRepliesWithWelcome(fakeWelcome, replies)
+}
+
+// serveRepliesWithWelcome is like serveReplies but writes the welcome banner passed
+// (or none at all if it is empty)
+func serveRepliesWithWelcome(welcome string, replies map[string]string) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		if welcome != "" {
+			if _, err := conn.Write([]byte(welcome)); err != nil {
+				return
+			}
+		}
+
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			reply, found := replies[strings.TrimSpace(scanner.Text())]
+			if !found {
+				reply = "9001 syntax error\n"
+			}
+
+			if _, err := conn.Write([]byte(reply)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// pipeDialer returns a dialer connecting to an in-memory fake Bird
+// which calls handle for every established connection
+func pipeDialer(handle func(conn net.Conn)) func(ctx context.Context) (net.Conn, error) {
+	return func(ctx context.Context) (net.Conn, error) {
+		client, server := net.Pipe()
+
+		go func() {
+			defer server.Close()
+			handle(server)
+		}()
+
+		return client, nil
+	}
+}
+
+// connectFakeBird starts an in-memory fake Bird answering with the registered replies
+// and returns a socket connected to it
+func connectFakeBird(t *testing.T, replies map[string]string, opts ...Option) *BirdSocket {
+	s := NewSocket("", append(opts, WithDialer(pipeDialer(serveReplies(replies))))...)
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+
+	return s
+}
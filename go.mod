@@ -1,5 +1,5 @@
 module github.com/kargh/bird_socket
 
-go 1.15
+go 1.18
 
 require github.com/czerwonk/testutils v0.0.0-20170526233935-dd9dabe360d4
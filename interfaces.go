@@ -0,0 +1,147 @@
+package birdsocket
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var interfaceLineRegex *regexp.Regexp
+
+func init() {
+	// e.g. `eth0 up (index=2)` or `eth0 up (index=2, master=#0)`
+	interfaceLineRegex = regexp.MustCompile(`^(\S+)\s+(\S+)(?:\s+\(index=(\d+)[^)]*\))?`)
+}
+
+// Interface is a network interface as listed by `show interfaces`.
+// State is up or down, Flags are the flags listed by Bird like LinkUp or Loopback
+type Interface struct {
+	Name      string
+	Index     int
+	State     string
+	MTU       int
+	Flags     []string
+	Addresses []InterfaceAddress
+}
+
+// InterfaceAddress is an IPv4 or IPv6 address assigned to an interface.
+// Preferred is set for the preferred (BIRD 1.x: primary) address of its family,
+// Scope is the scope of the address like site, link or host
+type InterfaceAddress struct {
+	Prefix    string
+	Preferred bool
+	Scope     string
+}
+
+// ShowInterfaces queries the interfaces known to Bird along with their addresses
+func (s *BirdSocket) ShowInterfaces() ([]Interface, error) {
+	r, err := s.QueryChecked("show interfaces")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseInterfaces(r), nil
+}
+
+// ShowInterfacesSummary queries the interfaces known to Bird using `show interfaces summary`,
+// which lists the state and the preferred addresses only. Index, MTU and flags are left empty
+func (s *BirdSocket) ShowInterfacesSummary() ([]Interface, error) {
+	r, err := s.QueryChecked("show interfaces summary")
+	if err != nil {
+		return nil, err
+	}
+
+	interfaces := make([]Interface, 0)
+	for _, l := range r.Lines {
+		if l.Code != CodeInterfaceSummary {
+			continue
+		}
+
+		// e.g. `eth0       up     192.168.1.9/24     2001:db8::9/64`
+		f := strings.Fields(l.Message)
+		if len(f) < 2 {
+			continue
+		}
+
+		iface := Interface{Name: f[0], State: f[1], Addresses: make([]InterfaceAddress, 0)}
+		for _, prefix := range f[2:] {
+			iface.Addresses = append(iface.Addresses, InterfaceAddress{Prefix: prefix, Preferred: true})
+		}
+		interfaces = append(interfaces, iface)
+	}
+
+	return interfaces, nil
+}
+
+func parseInterfaces(r *Reply) []Interface {
+	interfaces := make([]Interface, 0)
+
+	for _, l := range r.Lines {
+		msg := strings.TrimSpace(l.Message)
+		if l.Code == CodeInterfaceList {
+			if iface, ok := parseInterfaceLine(msg); ok {
+				interfaces = append(interfaces, iface)
+			}
+			continue
+		}
+
+		if len(interfaces) == 0 || len(msg) == 0 {
+			continue
+		}
+		iface := &interfaces[len(interfaces)-1]
+
+		switch l.Code {
+		case CodeInterfaceFlags:
+			parseInterfaceFlags(msg, iface)
+		case CodeInterfaceAddress:
+			iface.Addresses = append(iface.Addresses, parseInterfaceAddress(msg))
+		}
+	}
+
+	return interfaces
+}
+
+func parseInterfaceLine(line string) (Interface, bool) {
+	m := interfaceLineRegex.FindStringSubmatch(line)
+	if m == nil {
+		return Interface{}, false
+	}
+
+	iface := Interface{Name: m[1], State: m[2], Flags: make([]string, 0), Addresses: make([]InterfaceAddress, 0)}
+	iface.Index, _ = strconv.Atoi(m[3])
+
+	return iface, true
+}
+
+// parseInterfaceFlags parses a line like `MultiAccess Broadcast Multicast AdminUp LinkUp MTU=1500`
+func parseInterfaceFlags(line string, iface *Interface) {
+	for _, f := range strings.Fields(line) {
+		if strings.HasPrefix(f, "MTU=") {
+			iface.MTU, _ = strconv.Atoi(strings.TrimPrefix(f, "MTU="))
+			continue
+		}
+
+		iface.Flags = append(iface.Flags, f)
+	}
+}
+
+// parseInterfaceAddress parses a line like `192.168.1.9/24 (Preferred, scope site)`
+func parseInterfaceAddress(line string) InterfaceAddress {
+	addr := InterfaceAddress{}
+
+	f := strings.Fields(line)
+	addr.Prefix = f[0]
+
+	attrs := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, f[0])), "()")
+	for _, a := range strings.Split(attrs, ",") {
+		a = strings.TrimSpace(a)
+		switch {
+		case a == "Preferred" || a == "Primary":
+			addr.Preferred = true
+		case strings.HasPrefix(a, "scope "):
+			addr.Scope = strings.TrimPrefix(a, "scope ")
+		}
+	}
+
+	return addr
+}
@@ -0,0 +1,98 @@
+package birdsocket
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestShowInterfaces verifies parsing of `show interfaces` as sent by BIRD 2.0
+func TestShowInterfaces(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show interfaces": "1001-lo up (index=1)\n" +
+			"1004-\tMultiAccess AdminUp LinkUp Loopback Ignored MTU=65536\n" +
+			"1003-\t127.0.0.1/8 (Preferred, scope host)\n" +
+			" \t::1/128 (Preferred, scope host)\n" +
+			"1001-eth0 up (index=2, master=#0)\n" +
+			"1004-\tMultiAccess Broadcast Multicast AdminUp LinkUp MTU=1500\n" +
+			"1003-\t192.168.1.9/24 (Preferred, scope site)\n" +
+			" \t2001:db8::9/64 (Preferred, scope univ)\n" +
+			" \tfe80::5054:ff:fe12:3456/64 (scope link)\n" +
+			"1001-eth1 down (index=3)\n" +
+			"1004-\tMultiAccess Broadcast Multicast AdminUp LinkDown MTU=9000\n" +
+			"0000 \n",
+	})
+
+	interfaces, err := s.ShowInterfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("interfaces", 3, len(interfaces), t)
+
+	eth0 := interfaces[1]
+	assert.StringEqual("name", "eth0", eth0.Name, t)
+	assert.IntEqual("index", 2, eth0.Index, t)
+	assert.StringEqual("state", "up", eth0.State, t)
+	assert.IntEqual("mtu", 1500, eth0.MTU, t)
+	assert.StringEqual("flags", "MultiAccess Broadcast Multicast AdminUp LinkUp", strings.Join(eth0.Flags, " "), t)
+	assert.IntEqual("addresses", 3, len(eth0.Addresses), t)
+	assert.StringEqual("ipv4", "192.168.1.9/24", eth0.Addresses[0].Prefix, t)
+	assert.True("ipv4 preferred", eth0.Addresses[0].Preferred, t)
+	assert.StringEqual("ipv4 scope", "site", eth0.Addresses[0].Scope, t)
+	assert.StringEqual("ipv6", "2001:db8::9/64", eth0.Addresses[1].Prefix, t)
+	assert.StringEqual("link local", "fe80::5054:ff:fe12:3456/64", eth0.Addresses[2].Prefix, t)
+	assert.False("link local preferred", eth0.Addresses[2].Preferred, t)
+	assert.StringEqual("link local scope", "link", eth0.Addresses[2].Scope, t)
+
+	eth1 := interfaces[2]
+	assert.StringEqual("eth1 state", "down", eth1.State, t)
+	assert.IntEqual("eth1 mtu", 9000, eth1.MTU, t)
+	assert.IntEqual("eth1 addresses", 0, len(eth1.Addresses), t)
+}
+
+// TestShowInterfacesV1 verifies parsing of `show interfaces` as sent by BIRD 1.6
+func TestShowInterfacesV1(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show interfaces": "1001-eth0 up (index=2)\n" +
+			"1004-\tMultiAccess Broadcast Multicast AdminUp LinkUp MTU=1500\n" +
+			"1003-\t192.168.1.9/24 (Primary, scope site)\n" +
+			" \t10.0.0.1/8 (Unselected, scope site)\n" +
+			"0000 \n",
+	})
+
+	interfaces, err := s.ShowInterfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("interfaces", 1, len(interfaces), t)
+	assert.IntEqual("addresses", 2, len(interfaces[0].Addresses), t)
+	assert.True("primary", interfaces[0].Addresses[0].Preferred, t)
+	assert.False("unselected", interfaces[0].Addresses[1].Preferred, t)
+}
+
+// TestShowInterfacesSummary verifies parsing of `show interfaces summary`
+func TestShowInterfacesSummary(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show interfaces summary": "2005-Interface  State  IPv4 address       IPv6 address\n" +
+			"1005-lo         up     127.0.0.1/8        ::1/128\n" +
+			" eth0       up     192.168.1.9/24     2001:db8::9/64\n" +
+			" eth1       down\n" +
+			"0000 \n",
+	})
+
+	interfaces, err := s.ShowInterfacesSummary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("interfaces", 3, len(interfaces), t)
+	assert.StringEqual("name", "eth0", interfaces[1].Name, t)
+	assert.StringEqual("state", "up", interfaces[1].State, t)
+	assert.IntEqual("addresses", 2, len(interfaces[1].Addresses), t)
+	assert.StringEqual("ipv6", "2001:db8::9/64", interfaces[1].Addresses[1].Prefix, t)
+	assert.StringEqual("eth1 state", "down", interfaces[2].State, t)
+	assert.IntEqual("eth1 addresses", 0, len(interfaces[2].Addresses), t)
+}
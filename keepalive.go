@@ -0,0 +1,102 @@
+package birdsocket
+
+import (
+	"context"
+	"time"
+)
+
+// WithKeepAlive probes idle connections every interval by sending `show status`.
+// If the probe fails the connection is re-established, so that a connection silently
+// dropped while idle does not fail the next query. Probes are serialized with queries
+// and only sent if no query was sent within the last interval
+func WithKeepAlive(interval time.Duration) Option {
+	return func(s *BirdSocket) {
+		s.keepAlive = interval
+	}
+}
+
+// startKeepAlive starts probing the connection if enabled and not yet running
+func (s *BirdSocket) startKeepAlive() {
+	if s.keepAlive <= 0 {
+		return
+	}
+
+	s.keepAliveMu.Lock()
+	defer s.keepAliveMu.Unlock()
+
+	if s.keepAliveStop != nil {
+		return
+	}
+
+	s.keepAliveStop = make(chan struct{})
+	go s.keepAliveLoop(s.keepAliveStop)
+}
+
+// stopKeepAlive stops probing the connection
+func (s *BirdSocket) stopKeepAlive() {
+	s.keepAliveMu.Lock()
+	defer s.keepAliveMu.Unlock()
+
+	if s.keepAliveStop != nil {
+		close(s.keepAliveStop)
+		s.keepAliveStop = nil
+	}
+}
+
+func (s *BirdSocket) keepAliveLoop(stop <-chan struct{}) {
+	t := time.NewTicker(s.keepAlive)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			s.probe(stop)
+		}
+	}
+}
+
+// probe sends `show status` on an idle connection and reconnects if it fails.
+// The statistics of the last reply are kept, so LastCode still reports the last query
+func (s *BirdSocket) probe(stop <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if isStopped(stop) || s.conn == nil || time.Since(s.lastUsed) < s.keepAlive {
+		return
+	}
+
+	lastCode, lastReads, lastBytes := s.lastCode, s.lastReads, s.lastBytes
+	defer func() {
+		s.lastCode, s.lastReads, s.lastBytes = lastCode, lastReads, lastBytes
+	}()
+
+	readDeadline := s.readDeadline
+	if readDeadline == nil {
+		s.readDeadline = &s.keepAlive
+	}
+	err := s.ping()
+	s.readDeadline = readDeadline
+	if err == nil {
+		return
+	}
+
+	if s.logger != nil {
+		s.logger.Debugf("keep-alive probe failed: %v", err)
+	}
+
+	s.closeConn()
+	if _, err := s.connect(context.Background(), true); err != nil && s.logger != nil {
+		s.logger.Debugf("keep-alive reconnect failed: %v", err)
+	}
+}
+
+func isStopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
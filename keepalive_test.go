@@ -0,0 +1,122 @@
+package birdsocket
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestKeepAliveReconnects simulate a scenario in which the idle connection is dropped
+// and verifies that it is re-established before the next query
+func TestKeepAliveReconnects(t *testing.T) {
+	var dials int32
+	serve := serveReplies(map[string]string{
+		"show status": "0013 Daemon is up and running\n",
+	})
+	dial := pipeDialer(func(conn net.Conn) {
+		if atomic.LoadInt32(&dials) == 1 {
+			// drop the first connection right after the welcome
+			conn.Write([]byte(fakeWelcome))
+			return
+		}
+
+		serve(conn)
+	})
+
+	s := NewSocket("", WithDialer(countingDialer(dial, &dials)), WithKeepAlive(20*time.Millisecond), WithReadDeadline(5*time.Second))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&dials) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.IntEqual("dials", 2, int(atomic.LoadInt32(&dials)), t)
+
+	out, err := s.Query("show status", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("reply", "0013 Daemon is up and running\n", string(out), t)
+}
+
+// TestKeepAliveSkipsBusyConnections verifies that no probe is sent while queries are sent
+// more frequently than the interval, and that probing stops on Close
+func TestKeepAliveSkipsBusyConnections(t *testing.T) {
+	var probes int32
+	serve := serveReplies(map[string]string{
+		"show protocols": "0000 \n",
+		"show status":    "0013 Daemon is up and running\n",
+	})
+	dial := pipeDialer(func(conn net.Conn) {
+		serve(&countingConn{Conn: conn, qry: "show status\n", count: &probes})
+	})
+
+	s := NewSocket("", WithDialer(dial), WithKeepAlive(50*time.Millisecond), WithReadDeadline(5*time.Second))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if _, err := s.Query("show protocols", true); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.IntEqual("probes while busy", 0, int(atomic.LoadInt32(&probes)), t)
+
+	time.Sleep(200 * time.Millisecond)
+	assert.True("probes while idle", atomic.LoadInt32(&probes) > 0, t)
+
+	s.Close()
+	assert.True("keep-alive stopped", s.keepAliveStop == nil, t)
+}
+
+// TestKeepAliveKeepsLastCode verifies that probes do not overwrite the code of the last query
+func TestKeepAliveKeepsLastCode(t *testing.T) {
+	var probes int32
+	serve := serveReplies(map[string]string{
+		"show status": "0013 Daemon is up and running\n",
+	})
+	dial := pipeDialer(func(conn net.Conn) {
+		serve(&countingConn{Conn: conn, qry: "show status\n", count: &probes})
+	})
+
+	s := NewSocket("", WithDialer(dial), WithKeepAlive(20*time.Millisecond), WithReadDeadline(5*time.Second))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err := s.Query("show foo", true); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&probes) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True("probes sent", atomic.LoadInt32(&probes) >= 2, t)
+	assert.IntEqual("last code", CodeSyntaxError, s.LastCode(), t)
+}
+
+// countingConn counts the reads of qry
+type countingConn struct {
+	net.Conn
+	qry   string
+	count *int32
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if string(b[:n]) == c.qry {
+		atomic.AddInt32(c.count, 1)
+	}
+
+	return n, err
+}
@@ -0,0 +1,39 @@
+package birdsocket
+
+import (
+	"fmt"
+	"io"
+)
+
+// Logger receives debug events emitted while communicating with Bird
+type Logger interface {
+	Debugf(format string, args ...interface{})
+}
+
+// WithLogger sets a logger receiving debug events about connects, sent queries and read replies.
+// No events are emitted if no logger is set
+func WithLogger(l Logger) Option {
+	return func(s *BirdSocket) {
+		s.logger = l
+	}
+}
+
+// WithRawDump writes every chunk of bytes read from and written to the connection to w
+// for debugging. Each chunk is preceded by a line holding the direction
+// (`<<<` for bytes read, `>>>` for bytes written) and its length, e.g. `<<< 24`,
+// followed by the bytes exactly as received or sent. Errors writing to w are ignored
+func WithRawDump(w io.Writer) Option {
+	return func(s *BirdSocket) {
+		s.rawDump = w
+	}
+}
+
+// dump writes b to the raw dump (if any) preceded by the direction marker
+func (s *BirdSocket) dump(marker string, b []byte) {
+	if s.rawDump == nil || len(b) == 0 {
+		return
+	}
+
+	fmt.Fprintf(s.rawDump, "%s %d\n", marker, len(b))
+	s.rawDump.Write(b)
+}
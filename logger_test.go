@@ -0,0 +1,65 @@
+package birdsocket
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+type recordingLogger struct {
+	events []string
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) {
+	l.events = append(l.events, fmt.Sprintf(format, args...))
+}
+
+// TestLogger verifies the events emitted for a single query
+func TestLogger(t *testing.T) {
+	l := &recordingLogger{}
+	s := connectFakeBird(t, map[string]string{
+		"show status": "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+	}, WithLogger(l))
+
+	l.events = nil
+	_, err := s.Query("show status", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("events", strings.Join([]string{
+		`sending query "show status"`,
+		"read 46 bytes",
+		"reply completed with code 0013",
+	}, "\n"), strings.Join(l.events, "\n"), t)
+}
+
+// TestRawDump verifies that the bytes read and written are dumped unmodified
+func TestRawDump(t *testing.T) {
+	chunks := []string{
+		"1007-10.0.0.0/8 via 192.168.1.1 on eth0 [sta",
+		"tic1 2018-12-21] * (200)\r\n",
+		"0000 \n",
+	}
+
+	var dump strings.Builder
+	s := NewSocket("", WithRawDump(&dump), WithDialer(pipeDialer(serveChunks(chunks...))))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	out, err := s.Query("show route", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("reply", "1007-10.0.0.0/8 via 192.168.1.1 on eth0 [static1 2018-12-21] * (200)\n0000 \n", string(out), t)
+
+	expected := fmt.Sprintf("<<< %d\n%s", len(fakeWelcome), fakeWelcome) + ">>> 11\nshow route\n"
+	for _, c := range chunks {
+		expected += fmt.Sprintf("<<< %d\n%s", len(c), c)
+	}
+	assert.StringEqual("dump", expected, dump.String(), t)
+}
@@ -0,0 +1,117 @@
+package birdsocket
+
+import (
+	"strconv"
+	"strings"
+)
+
+var sizeUnits = map[string]int64{
+	"B":  1,
+	"kB": 1 << 10,
+	"MB": 1 << 20,
+	"GB": 1 << 30,
+	"TB": 1 << 40,
+}
+
+// MemoryUsage is the memory used by a category in bytes.
+// Overhead is only reported by BIRD 2.0.8 and later
+type MemoryUsage struct {
+	Effective int64
+	Overhead  int64
+}
+
+// Memory is the parsed reply of `show memory`
+type Memory struct {
+	RoutingTables   MemoryUsage
+	RouteAttributes MemoryUsage
+	ROATables       MemoryUsage
+	Protocols       MemoryUsage
+	CurrentConfig   MemoryUsage
+	StandbyMemory   MemoryUsage
+	Total           MemoryUsage
+
+	// Other holds categories not known by this package, keyed by their label
+	Other map[string]MemoryUsage
+}
+
+// ShowMemory queries the memory usage of the Bird daemon
+func (s *BirdSocket) ShowMemory() (*Memory, error) {
+	r, err := s.QueryChecked("show memory")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMemory(r), nil
+}
+
+func parseMemory(r *Reply) *Memory {
+	m := &Memory{Other: make(map[string]MemoryUsage)}
+
+	for _, l := range r.Lines {
+		if l.Code != CodeMemory {
+			continue
+		}
+
+		key, value := splitKeyValue(l.Message)
+		u, ok := parseMemoryUsage(value)
+		if !ok {
+			continue
+		}
+
+		switch strings.ToLower(key) {
+		case "routing tables":
+			m.RoutingTables = u
+		case "route attributes":
+			m.RouteAttributes = u
+		case "roa tables":
+			m.ROATables = u
+		case "protocols":
+			m.Protocols = u
+		case "current config":
+			m.CurrentConfig = u
+		case "standby memory":
+			m.StandbyMemory = u
+		case "total":
+			m.Total = u
+		default:
+			m.Other[key] = u
+		}
+	}
+
+	return m
+}
+
+// parseMemoryUsage parses values like `148 kB` or `93 kB    18 kB`
+func parseMemoryUsage(s string) (MemoryUsage, bool) {
+	f := strings.Fields(s)
+	if len(f) < 2 {
+		return MemoryUsage{}, false
+	}
+
+	effective, ok := parseSize(f[0], f[1])
+	if !ok {
+		return MemoryUsage{}, false
+	}
+
+	u := MemoryUsage{Effective: effective}
+	if len(f) >= 4 {
+		u.Overhead, _ = parseSize(f[2], f[3])
+	}
+
+	return u, true
+}
+
+// parseSize converts a size printed by Bird to bytes
+func parseSize(value, unit string) (int64, bool) {
+	mult, found := sizeUnits[unit]
+	if !found {
+		return 0, false
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return int64(n * float64(mult)), true
+}
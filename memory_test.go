@@ -0,0 +1,62 @@
+package birdsocket
+
+import (
+	"testing"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestShowMemoryV1 verifies parsing of `show memory` as sent by BIRD 1.6
+func TestShowMemoryV1(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show memory": "1018-BIRD memory usage\n" +
+			"  Routing tables:    148 kB\n" +
+			"  Route attributes:   59 kB\n" +
+			"  ROA tables:        192  B\n" +
+			"  Protocols:          58 kB\n" +
+			"  Total:             4160 kB\n" +
+			"0000 \n",
+	})
+
+	m, err := s.ShowMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Int64Equal("routing tables", 148*1024, m.RoutingTables.Effective, t)
+	assert.Int64Equal("route attributes", 59*1024, m.RouteAttributes.Effective, t)
+	assert.Int64Equal("roa tables", 192, m.ROATables.Effective, t)
+	assert.Int64Equal("protocols", 58*1024, m.Protocols.Effective, t)
+	assert.Int64Equal("total", 4160*1024, m.Total.Effective, t)
+	assert.Int64Equal("total overhead", 0, m.Total.Overhead, t)
+	assert.IntEqual("other", 0, len(m.Other), t)
+}
+
+// TestShowMemoryV2 verifies parsing of `show memory` as sent by BIRD 2.0.8 and later
+func TestShowMemoryV2(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show memory": "1018-BIRD memory usage\n" +
+			"                  Effective    Overhead\n" +
+			" Routing tables:    93.5 kB      18.3 kB\n" +
+			" Route attributes:    35 kB      10 kB\n" +
+			" Protocols:           64 kB       8 kB\n" +
+			" Current config:     106 kB      12 kB\n" +
+			" Standby memory:       0 B        0 B\n" +
+			" Attribute cache:      3 kB       1 kB\n" +
+			" Total:              2.5 MB     467 kB\n" +
+			"0000 \n",
+	})
+
+	m, err := s.ShowMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Int64Equal("routing tables", 95744, m.RoutingTables.Effective, t)
+	assert.Int64Equal("routing tables overhead", 18739, m.RoutingTables.Overhead, t)
+	assert.Int64Equal("current config", 106*1024, m.CurrentConfig.Effective, t)
+	assert.Int64Equal("standby memory", 0, m.StandbyMemory.Effective, t)
+	assert.Int64Equal("total", 2621440, m.Total.Effective, t)
+	assert.Int64Equal("total overhead", 467*1024, m.Total.Overhead, t)
+	assert.Int64Equal("attribute cache", 3*1024, m.Other["Attribute cache"].Effective, t)
+}
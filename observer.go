@@ -0,0 +1,23 @@
+package birdsocket
+
+import "time"
+
+// Observer is notified about every query sent to Bird, e.g. to collect metrics
+type Observer interface {
+	// OnQuery is called once per query after the reply was read or the query failed
+	OnQuery(qry string, dur time.Duration, bytes int, err error)
+}
+
+// WithObserver sets an observer notified about every query
+func WithObserver(o Observer) Option {
+	return func(s *BirdSocket) {
+		s.observer = o
+	}
+}
+
+// observe notifies the observer (if any) about a query started at start
+func (s *BirdSocket) observe(qry string, start time.Time, b []byte, err error) {
+	if s.observer != nil {
+		s.observer.OnQuery(qry, time.Since(start), len(b), err)
+	}
+}
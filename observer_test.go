@@ -0,0 +1,70 @@
+package birdsocket
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+type observedQuery struct {
+	qry   string
+	dur   time.Duration
+	bytes int
+	err   error
+}
+
+type recordingObserver struct {
+	queries []observedQuery
+}
+
+func (o *recordingObserver) OnQuery(qry string, dur time.Duration, bytes int, err error) {
+	o.queries = append(o.queries, observedQuery{qry: qry, dur: dur, bytes: bytes, err: err})
+}
+
+// TestObserver verifies that the observer is notified once per query
+// with the duration and the size of the reply
+func TestObserver(t *testing.T) {
+	o := &recordingObserver{}
+	s := NewSocket("", WithObserver(o), WithDialer(pipeDialer(func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+
+		r := bufio.NewReader(conn)
+		for {
+			qry, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			if strings.HasPrefix(qry, "show route") {
+				time.Sleep(50 * time.Millisecond)
+				conn.Write([]byte("1007-10.0.0.0/8 via 192.168.1.1 on eth0 [static1 2018-12-21] * (200)\n0000 \n"))
+				continue
+			}
+
+			conn.Write([]byte("0013 Daemon is up and running\n"))
+		}
+	})))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, qry := range []string{"show route", "show status"} {
+		if _, err := s.Query(qry, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.Close()
+	_, err := s.Query("show status", true)
+
+	assert.IntEqual("queries", 3, len(o.queries), t)
+	assert.StringEqual("query 0", "show route", o.queries[0].qry, t)
+	assert.IntEqual("query 0 bytes", 75, o.queries[0].bytes, t)
+	assert.IntEqual("query 1 bytes", 30, o.queries[1].bytes, t)
+	assert.True("query 0 slower than query 1", o.queries[0].dur > o.queries[1].dur, t)
+	assert.True("query 2 error", o.queries[2].err != nil && o.queries[2].err == err, t)
+}
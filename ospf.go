@@ -0,0 +1,105 @@
+package birdsocket
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OSPFNeighbor is a neighbor as listed by `show ospf neighbors`.
+// State is the state of the adjacency like Full or 2-Way,
+// Role is the role of the neighbor on the network like DR, BDR or Other
+type OSPFNeighbor struct {
+	Protocol  string
+	RouterID  string
+	Priority  int
+	State     string
+	Role      string
+	DeadTime  time.Duration
+	Interface string
+	RouterIP  string
+}
+
+// ShowOSPFNeighbors queries the neighbors of the OSPF protocol instance.
+// If protocol is empty the neighbors of all OSPF instances are returned
+func (s *BirdSocket) ShowOSPFNeighbors(protocol string) ([]OSPFNeighbor, error) {
+	r, err := s.QueryChecked(strings.TrimSpace("show ospf neighbors " + protocol))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseOSPFNeighbors(r, protocol), nil
+}
+
+func parseOSPFNeighbors(r *Reply, protocol string) []OSPFNeighbor {
+	neighbors := make([]OSPFNeighbor, 0)
+
+	for _, l := range r.Lines {
+		if l.Code != CodeOSPFNeighbors {
+			continue
+		}
+
+		msg := strings.TrimSpace(l.Message)
+		if strings.HasPrefix(msg, "Router ID") || len(msg) == 0 {
+			continue
+		}
+
+		f := strings.Fields(msg)
+		if len(f) == 1 && strings.HasSuffix(msg, ":") {
+			protocol = strings.TrimSuffix(msg, ":")
+			continue
+		}
+
+		if n, ok := parseOSPFNeighborLine(f); ok {
+			n.Protocol = protocol
+			neighbors = append(neighbors, n)
+		}
+	}
+
+	return neighbors
+}
+
+// parseOSPFNeighborLine parses lines like `192.168.1.2  1  Full/BDR  00:34  eth0  192.168.1.2`
+func parseOSPFNeighborLine(f []string) (OSPFNeighbor, bool) {
+	if len(f) < 5 {
+		return OSPFNeighbor{}, false
+	}
+
+	n := OSPFNeighbor{RouterID: f[0], Interface: f[4]}
+	n.Priority, _ = strconv.Atoi(f[1])
+	n.State = f[2]
+	if i := strings.Index(f[2], "/"); i >= 0 {
+		n.State, n.Role = f[2][:i], f[2][i+1:]
+	}
+	n.DeadTime = parseDeadTime(f[3])
+
+	if len(f) > 5 {
+		n.RouterIP = f[5]
+	}
+
+	return n, true
+}
+
+// parseDeadTime parses dead times like 00:34 (BIRD 1.x) or 32.505 (BIRD 2.0)
+func parseDeadTime(s string) time.Duration {
+	if i := strings.Index(s, ":"); i >= 0 {
+		min, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return 0
+		}
+
+		sec, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return 0
+		}
+
+		return time.Duration(min)*time.Minute + time.Duration(sec)*time.Second
+	}
+
+	sec, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+
+	return time.Duration(sec * float64(time.Second))
+}
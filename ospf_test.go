@@ -0,0 +1,67 @@
+package birdsocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestShowOSPFNeighbors verifies parsing of `show ospf neighbors` for a single instance
+func TestShowOSPFNeighbors(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show ospf neighbors ospf1": "1013-ospf1:\n" +
+			"1013-Router ID   \tPri\t     State     \tDTime\tInterface  Router IP   \n" +
+			"1013-192.168.1.2 \t  1\tFull/BDR      \t00:34\teth0       192.168.1.2\n" +
+			"1013-192.168.1.3 \t  0\t2-Way/Other   \t00:38\teth0       192.168.1.3\n" +
+			"0000 \n",
+	})
+
+	neighbors, err := s.ShowOSPFNeighbors("ospf1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("neighbors", 2, len(neighbors), t)
+
+	n := neighbors[0]
+	assert.StringEqual("protocol", "ospf1", n.Protocol, t)
+	assert.StringEqual("router id", "192.168.1.2", n.RouterID, t)
+	assert.IntEqual("priority", 1, n.Priority, t)
+	assert.StringEqual("state", "Full", n.State, t)
+	assert.StringEqual("role", "BDR", n.Role, t)
+	assert.True("dead time", n.DeadTime == 34*time.Second, t)
+	assert.StringEqual("interface", "eth0", n.Interface, t)
+	assert.StringEqual("router ip", "192.168.1.2", n.RouterIP, t)
+
+	n = neighbors[1]
+	assert.IntEqual("priority", 0, n.Priority, t)
+	assert.StringEqual("state", "2-Way", n.State, t)
+	assert.StringEqual("role", "Other", n.Role, t)
+}
+
+// TestShowOSPFNeighborsAllInstances verifies parsing of `show ospf neighbors`
+// listing the neighbors of multiple instances as sent by BIRD 2.0
+func TestShowOSPFNeighborsAllInstances(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show ospf neighbors": "1013-ospf4:\n" +
+			"1013-Router ID   \tPri\t     State     \tDTime\tInterface  Router IP   \n" +
+			"1013-10.0.0.2    \t  1\tFull/DR       \t32.505\teth0       10.0.0.2\n" +
+			"1013-\n" +
+			"1013-ospf6:\n" +
+			"1013-Router ID   \tPri\t     State     \tDTime\tInterface  Router IP   \n" +
+			"1013-10.0.0.2    \t  1\tFull/DR       \t35.120\teth0       fe80::2\n" +
+			"0000 \n",
+	})
+
+	neighbors, err := s.ShowOSPFNeighbors("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("neighbors", 2, len(neighbors), t)
+	assert.StringEqual("protocol", "ospf4", neighbors[0].Protocol, t)
+	assert.True("dead time", neighbors[0].DeadTime == 32505*time.Millisecond, t)
+	assert.StringEqual("protocol", "ospf6", neighbors[1].Protocol, t)
+	assert.StringEqual("router ip", "fe80::2", neighbors[1].RouterIP, t)
+}
@@ -0,0 +1,116 @@
+package birdsocket
+
+import "sync"
+
+// Pool maintains a set of connections to Bird which are reused across queries
+type Pool struct {
+	socketPath string
+	opts       []Option
+	idle       chan *BirdSocket
+	slots      chan struct{}
+	mu         sync.Mutex
+	closed     bool
+}
+
+// NewPool creates a pool holding up to size connections to the socket.
+// Sizes below 1 are treated as 1. The options are applied to every connection
+func NewPool(socketPath string, size int, opts ...Option) *Pool {
+	if size < 1 {
+		size = 1
+	}
+
+	return &Pool{
+		socketPath: socketPath,
+		opts:       opts,
+		idle:       make(chan *BirdSocket, size),
+		slots:      make(chan struct{}, size),
+	}
+}
+
+// Query sends an query to Bird using an idle connection of the pool and waits for the reply.
+// If all connections are in use, Query waits for one to be returned to the pool.
+// Connections failing during a query are discarded. If a reused connection turned out
+// to be broken, a `show` query is retried once on a new connection
+func (p *Pool) Query(qry string) ([]byte, error) {
+	p.slots <- struct{}{}
+	defer func() { <-p.slots }()
+
+	s, reused, err := p.get()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := s.Query(qry, true)
+	if err != nil && reused && isConnectionError(err) && isIdempotent(qry) {
+		s.Close()
+
+		s, err = p.dial()
+		if err != nil {
+			return nil, err
+		}
+		b, err = s.Query(qry, true)
+	}
+
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	p.put(s)
+	return b, nil
+}
+
+// Close closes all idle connections. Connections in use are closed as soon as they are returned
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for {
+		select {
+		case s := <-p.idle:
+			s.Close()
+		default:
+			return
+		}
+	}
+}
+
+func (p *Pool) get() (s *BirdSocket, reused bool, err error) {
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		return nil, false, ErrPoolClosed
+	}
+
+	select {
+	case s := <-p.idle:
+		return s, true, nil
+	default:
+		s, err := p.dial()
+		return s, false, err
+	}
+}
+
+func (p *Pool) dial() (*BirdSocket, error) {
+	s := NewSocket(p.socketPath, p.opts...)
+	if _, err := s.Connect(true); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (p *Pool) put(s *BirdSocket) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		s.Close()
+		return
+	}
+
+	p.idle <- s
+}
@@ -0,0 +1,92 @@
+package birdsocket
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// countingDialer wraps dial and counts the connections established
+func countingDialer(dial func(ctx context.Context) (net.Conn, error), count *int32) func(ctx context.Context) (net.Conn, error) {
+	return func(ctx context.Context) (net.Conn, error) {
+		atomic.AddInt32(count, 1)
+		return dial(ctx)
+	}
+}
+
+// TestPoolReusesConnections verifies that idle connections are reused
+func TestPoolReusesConnections(t *testing.T) {
+	var dials int32
+	dial := pipeDialer(serveReplies(map[string]string{
+		"show status": "0013 Daemon is up and running\n",
+	}))
+
+	p := NewPool("", 2, WithDialer(countingDialer(dial, &dials)))
+	defer p.Close()
+
+	for i := 0; i < 3; i++ {
+		out, err := p.Query("show status")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.StringEqual("reply", "0013 Daemon is up and running\n", string(out), t)
+	}
+
+	assert.IntEqual("dials", 1, int(atomic.LoadInt32(&dials)), t)
+}
+
+// TestPoolDiscardsBrokenConnections verifies that a connection closed by Bird
+// is replaced by a new one instead of being reused
+func TestPoolDiscardsBrokenConnections(t *testing.T) {
+	var dials int32
+	dial := pipeDialer(func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+		bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte("0013 Daemon is up and running\n"))
+	})
+
+	p := NewPool("", 1, WithDialer(countingDialer(dial, &dials)))
+	defer p.Close()
+
+	for i := 0; i < 2; i++ {
+		out, err := p.Query("show status")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.StringEqual("reply", "0013 Daemon is up and running\n", string(out), t)
+	}
+
+	assert.IntEqual("dials", 2, int(atomic.LoadInt32(&dials)), t)
+}
+
+// TestPoolClosed verifies that a closed pool can not be queried anymore
+func TestPoolClosed(t *testing.T) {
+	p := NewPool("", 1, WithDialer(pipeDialer(serveReplies(map[string]string{}))))
+	p.Close()
+
+	_, err := p.Query("show status")
+	assert.True("ErrPoolClosed returned", err == ErrPoolClosed, t)
+}
+
+// TestPoolInvalidSize verifies that pools created with sizes below 1 hold one connection
+func TestPoolInvalidSize(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		p := NewPool("", size, WithDialer(pipeDialer(serveReplies(map[string]string{
+			"show status": "0013 Daemon is up and running\n",
+		}))))
+
+		out, err := p.Query("show status")
+		p.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.StringEqual("reply", "0013 Daemon is up and running\n", string(out), t)
+	}
+}
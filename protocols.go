@@ -0,0 +1,392 @@
+package birdsocket
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Protocol is a protocol instance as listed by `show protocols`
+type Protocol struct {
+	Name  string
+	Proto string
+	Table string
+	State string
+	Since time.Time
+	Info  string
+
+	// Detail is only set by ShowProtocolsAll
+	Detail *ProtocolDetail
+}
+
+// ProtocolDetail holds the details listed by `show protocols all`.
+// For BIRD 2.x Preference, the filters and Routes are the ones of the first channel,
+// see Channels for all of them
+type ProtocolDetail struct {
+	Description  string
+	Preference   int
+	InputFilter  string
+	OutputFilter string
+	Routes       *RouteCounts
+
+	// Channels are the channels of the protocol (e.g. ipv4 and ipv6) as listed by BIRD 2.x,
+	// BIRD 1.x has no channels
+	Channels []Channel
+}
+
+// Channel is a channel connecting a protocol to a table as listed by `show protocols all`.
+// Routes is nil if no counters are listed for the channel
+type Channel struct {
+	Name         string
+	State        string
+	Table        string
+	Preference   int
+	InputFilter  string
+	OutputFilter string
+	Routes       *RouteCounts
+}
+
+// Channel returns the channel name (e.g. ipv4) or nil if the protocol has no such channel
+func (d *ProtocolDetail) Channel(name string) *Channel {
+	for i := range d.Channels {
+		if d.Channels[i].Name == name {
+			return &d.Channels[i]
+		}
+	}
+
+	return nil
+}
+
+// RouteCounts are the route counters of a protocol
+type RouteCounts struct {
+	Imported  int
+	Filtered  int
+	Exported  int
+	Preferred int
+}
+
+// ShowProtocols queries the list of protocol instances
+func (s *BirdSocket) ShowProtocols() ([]Protocol, error) {
+	r, err := s.QueryChecked("show protocols")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseProtocols(r), nil
+}
+
+// ShowProtocolsAll queries the list of protocol instances including their details
+func (s *BirdSocket) ShowProtocolsAll() ([]Protocol, error) {
+	r, err := s.QueryChecked("show protocols all")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseProtocols(r), nil
+}
+
+// showProtocolAll queries the protocol instance name including its details
+func (s *BirdSocket) showProtocolAll(name string) (*Protocol, *Reply, error) {
+	r, err := s.QueryChecked("show protocols all " + name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	protocols := parseProtocols(r)
+	if len(protocols) == 0 {
+		return nil, nil, fmt.Errorf("protocol %s not found", name)
+	}
+
+	return &protocols[0], r, nil
+}
+
+// EnableProtocol enables the protocol instance name.
+// If no protocol matches name, the error reported by Bird is returned as *BirdError.
+// ErrRestricted is returned without sending the command on restricted sessions
+func (s *BirdSocket) EnableProtocol(name string) error {
+	_, err := s.controlProtocol("enable", name, CodeEnabled, CodeAlreadyEnabled)
+	return err
+}
+
+// DisableProtocol disables the protocol instance name.
+// If no protocol matches name, the error reported by Bird is returned as *BirdError.
+// ErrRestricted is returned without sending the command on restricted sessions
+func (s *BirdSocket) DisableProtocol(name string) error {
+	_, err := s.controlProtocol("disable", name, CodeDisabled, CodeAlreadyDisabled)
+	return err
+}
+
+// RestartProtocol restarts the protocol instance name.
+// If no protocol matches name, the error reported by Bird is returned as *BirdError.
+// ErrRestricted is returned without sending the command on restricted sessions
+func (s *BirdSocket) RestartProtocol(name string) error {
+	_, err := s.controlProtocol("restart", name, CodeRestarted)
+	return err
+}
+
+// ReloadDirection selects the routes reloaded by ReloadProtocol
+type ReloadDirection int
+
+const (
+	// ReloadBoth reloads imported and exported routes
+	ReloadBoth ReloadDirection = iota
+
+	// ReloadIn reloads imported routes, re-evaluating the import filter
+	ReloadIn
+
+	// ReloadOut reloads exported routes, re-evaluating the export filter
+	ReloadOut
+)
+
+// ReloadProtocol reloads the routes of the protocol instance name in direction,
+// e.g. after changing a route policy. If Bird denies the command, the error returned
+// matches ErrAccessDenied. ErrRestricted is returned without sending the command on restricted sessions
+func (s *BirdSocket) ReloadProtocol(name string, direction ReloadDirection) error {
+	cmd := "reload"
+	switch direction {
+	case ReloadIn:
+		cmd = "reload in"
+	case ReloadOut:
+		cmd = "reload out"
+	}
+
+	_, err := s.controlProtocol(cmd, name, CodeReloading)
+	return err
+}
+
+// EnableProtocolsMatching enables all protocol instances matching the shell-like
+// pattern (e.g. `upstream*`) and returns the names of the protocols affected,
+// including those already enabled.
+// If no protocol matches, the error reported by Bird is returned as *BirdError
+func (s *BirdSocket) EnableProtocolsMatching(pattern string) ([]string, error) {
+	return s.controlProtocolsMatching("enable", pattern, CodeEnabled, CodeAlreadyEnabled)
+}
+
+// DisableProtocolsMatching disables all protocol instances matching the shell-like
+// pattern (e.g. `upstream*`) and returns the names of the protocols affected,
+// including those already disabled.
+// If no protocol matches, the error reported by Bird is returned as *BirdError
+func (s *BirdSocket) DisableProtocolsMatching(pattern string) ([]string, error) {
+	return s.controlProtocolsMatching("disable", pattern, CodeDisabled, CodeAlreadyDisabled)
+}
+
+// RestartProtocolsMatching restarts all protocol instances matching the shell-like
+// pattern (e.g. `upstream*`) and returns the names of the protocols affected.
+// If no protocol matches, the error reported by Bird is returned as *BirdError
+func (s *BirdSocket) RestartProtocolsMatching(pattern string) ([]string, error) {
+	return s.controlProtocolsMatching("restart", pattern, CodeRestarted)
+}
+
+// controlProtocolsMatching sends cmd for all protocols matching pattern,
+// which Bird expects to be passed as a quoted string
+func (s *BirdSocket) controlProtocolsMatching(cmd, pattern string, codes ...int) ([]string, error) {
+	if strings.ContainsAny(pattern, "\r\n") {
+		return nil, ErrMultilineQuery
+	}
+
+	if pattern == "" || strings.Contains(pattern, `"`) {
+		return nil, fmt.Errorf("invalid protocol pattern: %q", pattern)
+	}
+
+	return s.controlProtocol(cmd, `"`+pattern+`"`, codes...)
+}
+
+// controlProtocol sends cmd for the protocol instance name and expects every
+// protocol line of the reply to carry one of the codes. The names of the protocols
+// listed are returned
+func (s *BirdSocket) controlProtocol(cmd, name string, codes ...int) ([]string, error) {
+	if s.restricted {
+		return nil, ErrRestricted
+	}
+
+	r, err := s.QueryChecked(cmd + " " + name)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	for _, l := range r.Lines {
+		if l.Code == CodeReplyOK {
+			continue
+		}
+
+		if !containsCode(codes, l.Code) {
+			return nil, fmt.Errorf("unexpected reply to %s: %04d %s", cmd, l.Code, l.Message)
+		}
+
+		// e.g. `bgp1: disabled`
+		if i := strings.Index(l.Message, ":"); i > 0 {
+			names = append(names, l.Message[:i])
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("unexpected reply to %s: no protocol confirmed", cmd)
+	}
+
+	return names, nil
+}
+
+func containsCode(codes []int, code int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseProtocols(r *Reply) []Protocol {
+	protocols := make([]Protocol, 0)
+
+	for _, l := range r.Lines {
+		switch l.Code {
+		case CodeProtocolList:
+			if p, ok := parseProtocolLine(l.Message); ok {
+				protocols = append(protocols, p)
+			}
+		case CodeProtocolDetails:
+			if len(protocols) == 0 {
+				continue
+			}
+
+			p := &protocols[len(protocols)-1]
+			if p.Detail == nil {
+				p.Detail = &ProtocolDetail{}
+			}
+			parseProtocolDetailLine(l.Message, p.Detail)
+		}
+	}
+
+	return protocols
+}
+
+// parseProtocolLine parses a row of the protocol table, columns are separated by a variable number of spaces
+func parseProtocolLine(line string) (Protocol, bool) {
+	f := strings.Fields(line)
+	if len(f) < 4 {
+		return Protocol{}, false
+	}
+
+	p := Protocol{Name: f[0], Proto: f[1], Table: f[2], State: f[3]}
+	since, n := parseSince(f[4:])
+	p.Since = since
+	p.Info = strings.Join(f[4+n:], " ")
+
+	return p, true
+}
+
+// parseSince parses the timestamp at the beginning of f, which may be split into date and time.
+// It returns the timestamp and the number of fields consumed
+func parseSince(f []string) (time.Time, int) {
+	if len(f) == 0 {
+		return time.Time{}, 0
+	}
+
+	if len(f) > 1 && strings.Contains(f[1], ":") && !strings.Contains(f[0], ":") {
+		if t := parseTime(f[0] + " " + f[1]); !t.IsZero() {
+			return t, 2
+		}
+	}
+
+	if strings.Contains(f[0], ":") {
+		// timestamps of the current day are printed without date
+		d, err := time.ParseInLocation("15:04:05", f[0], time.Local)
+		if err != nil {
+			return time.Time{}, 0
+		}
+
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), d.Hour(), d.Minute(), d.Second(), d.Nanosecond(), time.Local), 1
+	}
+
+	if t := parseTime(f[0]); !t.IsZero() {
+		return t, 1
+	}
+
+	return time.Time{}, 0
+}
+
+func parseProtocolDetailLine(line string, d *ProtocolDetail) {
+	key, value := splitKeyValue(line)
+
+	if strings.HasPrefix(key, "Channel ") && value == "" {
+		d.Channels = append(d.Channels, Channel{Name: strings.TrimPrefix(key, "Channel ")})
+		return
+	}
+
+	if len(d.Channels) > 0 {
+		parseChannelLine(key, value, &d.Channels[len(d.Channels)-1])
+		if len(d.Channels) > 1 {
+			return
+		}
+	}
+
+	switch key {
+	case "Description":
+		d.Description = value
+	case "Preference":
+		d.Preference, _ = strconv.Atoi(value)
+	case "Input filter":
+		d.InputFilter = value
+	case "Output filter":
+		d.OutputFilter = value
+	case "Routes":
+		d.Routes = parseRouteCounts(value)
+	}
+}
+
+func parseChannelLine(key, value string, c *Channel) {
+	switch key {
+	case "State":
+		c.State = value
+	case "Table":
+		c.Table = value
+	case "Preference":
+		c.Preference, _ = strconv.Atoi(value)
+	case "Input filter":
+		c.InputFilter = value
+	case "Output filter":
+		c.OutputFilter = value
+	case "Routes":
+		c.Routes = parseRouteCounts(value)
+	}
+}
+
+// splitKeyValue splits a `key: value` detail line
+func splitKeyValue(line string) (key, value string) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return strings.TrimSpace(line), ""
+	}
+
+	return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+}
+
+// parseRouteCounts parses counters like `10 imported, 2 filtered, 0 exported, 10 preferred`
+func parseRouteCounts(s string) *RouteCounts {
+	c := &RouteCounts{}
+
+	for _, part := range strings.Split(s, ",") {
+		var n int
+		var name string
+		if _, err := fmt.Sscanf(strings.TrimSpace(part), "%d %s", &n, &name); err != nil {
+			continue
+		}
+
+		switch name {
+		case "imported":
+			c.Imported = n
+		case "filtered":
+			c.Filtered = n
+		case "exported":
+			c.Exported = n
+		case "preferred":
+			c.Preferred = n
+		}
+	}
+
+	return c
+}
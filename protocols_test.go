@@ -0,0 +1,306 @@
+package birdsocket
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestShowProtocolsV1 verifies parsing of `show protocols` as sent by BIRD 1.6
+func TestShowProtocolsV1(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show protocols": "2002-name     proto    table    state  since       info\n" +
+			"1002-device1  Device   master   up     2018-12-21 12:35:11\n" +
+			" direct1  Direct   master   up     2018-12-21 12:35:11\n" +
+			" kernel1  Kernel   master   up     2018-12-21 12:35:11\n" +
+			" SAR2     BGP      master   down   2018-12-21 12:35:11  Error: Invalid next hop\n" +
+			" SAR1     BGP      master   start  2018-12-21  Connect\n" +
+			"0000 \n",
+	})
+
+	protocols, err := s.ShowProtocols()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("protocols", 5, len(protocols), t)
+
+	p := protocols[0]
+	assert.StringEqual("name", "device1", p.Name, t)
+	assert.StringEqual("proto", "Device", p.Proto, t)
+	assert.StringEqual("table", "master", p.Table, t)
+	assert.StringEqual("state", "up", p.State, t)
+	assert.True("since", p.Since.Equal(time.Date(2018, 12, 21, 12, 35, 11, 0, time.Local)), t)
+	assert.StringEqual("info", "", p.Info, t)
+	assert.True("no detail", p.Detail == nil, t)
+
+	p = protocols[3]
+	assert.StringEqual("name", "SAR2", p.Name, t)
+	assert.StringEqual("state", "down", p.State, t)
+	assert.StringEqual("info", "Error: Invalid next hop", p.Info, t)
+
+	p = protocols[4]
+	assert.StringEqual("state", "start", p.State, t)
+	assert.True("since", p.Since.Equal(time.Date(2018, 12, 21, 0, 0, 0, 0, time.Local)), t)
+	assert.StringEqual("info", "Connect", p.Info, t)
+}
+
+// TestShowProtocolsV2 verifies parsing of `show protocols` as sent by BIRD 2.0
+func TestShowProtocolsV2(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show protocols": "2002-Name       Proto      Table      State  Since         Info\n" +
+			"1002-device1    Device     ---        up     2021-02-28 09:00:00.123  \n" +
+			" kernel1    Kernel     master4    up     2021-02-28 09:00:00.123  \n" +
+			" upstream1  BGP        ---        up     2021-02-28 09:00:05.456  Established   \n" +
+			"0000 \n",
+	})
+
+	protocols, err := s.ShowProtocols()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("protocols", 3, len(protocols), t)
+
+	p := protocols[2]
+	assert.StringEqual("name", "upstream1", p.Name, t)
+	assert.StringEqual("proto", "BGP", p.Proto, t)
+	assert.StringEqual("table", "---", p.Table, t)
+	assert.True("since", p.Since.Equal(time.Date(2021, 2, 28, 9, 0, 5, 456000000, time.Local)), t)
+	assert.StringEqual("info", "Established", p.Info, t)
+}
+
+// TestShowProtocolsAll verifies parsing of the details listed by `show protocols all`
+func TestShowProtocolsAll(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show protocols all": "2002-name     proto    table    state  since       info\n" +
+			"1002-device1  Device   master   up     2018-12-21 12:35:11\n" +
+			"1006-  Preference:     240\n" +
+			"  Input filter:   ACCEPT\n" +
+			"  Output filter:  REJECT\n" +
+			"  Routes:         0 imported, 0 exported, 0 preferred\n" +
+			"\n" +
+			"1002-upstream1 BGP     master   up     2018-12-21 12:35:11  Established\n" +
+			"1006-  Description:    Upstream provider\n" +
+			"  Preference:     100\n" +
+			"  Input filter:   import_upstream\n" +
+			"  Output filter:  export_upstream\n" +
+			"  Routes:         650000 imported, 12 filtered, 10 exported, 640000 preferred\n" +
+			"  Route change stats:     received   rejected   filtered    ignored   accepted\n" +
+			"    Import updates:         700000          0         12          0     650000\n" +
+			"\n" +
+			"0000 \n",
+	})
+
+	protocols, err := s.ShowProtocolsAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("protocols", 2, len(protocols), t)
+	assert.True("device1 detail", protocols[0].Detail != nil, t)
+	assert.IntEqual("device1 preference", 240, protocols[0].Detail.Preference, t)
+
+	p := protocols[1]
+	assert.StringEqual("name", "upstream1", p.Name, t)
+	assert.StringEqual("description", "Upstream provider", p.Detail.Description, t)
+	assert.IntEqual("preference", 100, p.Detail.Preference, t)
+	assert.StringEqual("input filter", "import_upstream", p.Detail.InputFilter, t)
+	assert.StringEqual("output filter", "export_upstream", p.Detail.OutputFilter, t)
+	assert.IntEqual("imported", 650000, p.Detail.Routes.Imported, t)
+	assert.IntEqual("filtered", 12, p.Detail.Routes.Filtered, t)
+	assert.IntEqual("exported", 10, p.Detail.Routes.Exported, t)
+	assert.IntEqual("preferred", 640000, p.Detail.Routes.Preferred, t)
+	assert.IntEqual("channels", 0, len(p.Detail.Channels), t)
+}
+
+// TestProtocolControl verifies enabling, disabling and restarting protocols
+func TestProtocolControl(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"enable bgp1":   "0011-bgp1: enabled\n0000 \n",
+		"enable bgp2":   "0010-bgp2: already enabled\n0000 \n",
+		"disable bgp1":  "0009-bgp1: disabled\n0000 \n",
+		"disable bgp2":  "0008-bgp2: already disabled\n0000 \n",
+		"restart bgp*":  "0012-bgp1: restarted\n0012-bgp2: restarted\n0000 \n",
+		"enable bgp3":   "8003 No protocols match\n",
+		"restart bgp3":  "8003 No protocols match\n",
+		"disable bgp4":  "0011-bgp4: enabled\n0000 \n",
+		"disable bgp99": "0000 \n",
+	})
+
+	for _, err := range []error{
+		s.EnableProtocol("bgp1"),
+		s.EnableProtocol("bgp2"),
+		s.DisableProtocol("bgp1"),
+		s.DisableProtocol("bgp2"),
+		s.RestartProtocol("bgp*"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, err := range []error{s.EnableProtocol("bgp3"), s.RestartProtocol("bgp3")} {
+		birdErr, ok := err.(*BirdError)
+		assert.True("*BirdError returned", ok, t)
+		assert.IntEqual("code", CodeNoProtocolsMatch, birdErr.Code, t)
+	}
+
+	assert.True("unexpected code rejected", s.DisableProtocol("bgp4") != nil, t)
+	assert.True("missing confirmation rejected", s.DisableProtocol("bgp99") != nil, t)
+}
+
+// TestProtocolControlRestricted verifies that protocol control commands
+// are refused on restricted sessions without being sent
+func TestProtocolControlRestricted(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"restrict": "0016 Access restricted\n",
+	}, WithRestricted())
+
+	assert.True("enable", errors.Is(s.EnableProtocol("bgp1"), ErrAccessDenied), t)
+	assert.True("disable", errors.Is(s.DisableProtocol("bgp1"), ErrAccessDenied), t)
+	assert.True("restart", errors.Is(s.RestartProtocol("bgp1"), ErrAccessDenied), t)
+	assert.True("reload", errors.Is(s.ReloadProtocol("bgp1", ReloadIn), ErrAccessDenied), t)
+}
+
+// TestProtocolControlMatching verifies controlling all protocols matching a pattern
+func TestProtocolControlMatching(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		`disable "upstream*"`: "0009-upstream1: disabled\n" +
+			"0008-upstream2: already disabled\n" +
+			"0009-upstream3: disabled\n" +
+			"0000 \n",
+		`enable "upstream*"`:  "0011-upstream1: enabled\n0000 \n",
+		`restart "upstream*"`: "0012-upstream1: restarted\n0012-upstream2: restarted\n0000 \n",
+		`disable "none*"`:     "8003 No protocols match\n",
+	})
+
+	names, err := s.DisableProtocolsMatching("upstream*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("disabled", "upstream1,upstream2,upstream3", strings.Join(names, ","), t)
+
+	names, err = s.EnableProtocolsMatching("upstream*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("enabled", "upstream1", strings.Join(names, ","), t)
+
+	names, err = s.RestartProtocolsMatching("upstream*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("restarted", "upstream1,upstream2", strings.Join(names, ","), t)
+
+	_, err = s.DisableProtocolsMatching("none*")
+	birdErr, ok := err.(*BirdError)
+	assert.True("*BirdError returned", ok, t)
+	if ok {
+		assert.IntEqual("code", CodeNoProtocolsMatch, birdErr.Code, t)
+	}
+
+	_, err = s.DisableProtocolsMatching("upstream*\nshow status")
+	assert.True("ErrMultilineQuery", err == ErrMultilineQuery, t)
+
+	_, err = s.DisableProtocolsMatching(`upstream" all`)
+	assert.True("quote rejected", err != nil, t)
+}
+
+// TestReloadProtocol verifies reloading the routes of a protocol in every direction
+func TestReloadProtocol(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"reload bgp1":     "0015-bgp1: reloading\n0000 \n",
+		"reload in bgp1":  "0015-bgp1: reloading\n0000 \n",
+		"reload out bgp1": "0015-bgp1: reloading\n0000 \n",
+		"reload in bgp2":  "8006-bgp2: reload failed\n0000 \n",
+		"reload bgp3":     "8007 Access denied\n",
+	})
+
+	for _, d := range []ReloadDirection{ReloadBoth, ReloadIn, ReloadOut} {
+		if err := s.ReloadProtocol("bgp1", d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assert.True("reload failed", s.ReloadProtocol("bgp2", ReloadIn) != nil, t)
+
+	err := s.ReloadProtocol("bgp3", ReloadBoth)
+	assert.True("ErrAccessDenied", errors.Is(err, ErrAccessDenied), t)
+}
+
+// TestShowProtocolsAllChannels verifies parsing of the route counters
+// per channel as listed by BIRD 2.0 and without channels as listed by BIRD 1.6
+func TestShowProtocolsAllChannels(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show protocols all": "2002-Name       Proto      Table      State  Since         Info\n" +
+			"1002-device1    Device     ---        up     2021-02-28 09:00:00\n" +
+			"\n" +
+			"1002-upstream1  BGP        ---        up     2021-02-28 09:00:05  Established\n" +
+			"1006-  BGP state:          Established\n" +
+			"    Neighbor address: 192.168.1.2\n" +
+			"  Channel ipv4\n" +
+			"    State:          UP\n" +
+			"    Table:          master4\n" +
+			"    Preference:     100\n" +
+			"    Input filter:   import_v4\n" +
+			"    Output filter:  export_v4\n" +
+			"    Routes:         650000 imported, 10 exported, 640000 preferred\n" +
+			"    Route change stats:     received   rejected   filtered    ignored   accepted\n" +
+			"      Import updates:         700000          0         12          0     650000\n" +
+			"  Channel ipv6\n" +
+			"    State:          UP\n" +
+			"    Table:          master6\n" +
+			"    Preference:     110\n" +
+			"    Input filter:   import_v6\n" +
+			"    Output filter:  export_v6\n" +
+			"    Routes:         120000 imported, 4 filtered, 2 exported, 110000 preferred\n" +
+			"\n" +
+			"1002-static1    Static     master4    up     2021-02-28 09:00:00\n" +
+			"1006-  Channel ipv4\n" +
+			"    State:          DOWN\n" +
+			"    Table:          master4\n" +
+			"\n" +
+			"0000 \n",
+	})
+
+	protocols, err := s.ShowProtocolsAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("protocols", 3, len(protocols), t)
+	assert.True("device1 without detail", protocols[0].Detail == nil, t)
+
+	d := protocols[1].Detail
+	assert.IntEqual("channels", 2, len(d.Channels), t)
+	assert.IntEqual("first channel routes", 650000, d.Routes.Imported, t)
+	assert.IntEqual("first channel preference", 100, d.Preference, t)
+	assert.StringEqual("first channel input filter", "import_v4", d.InputFilter, t)
+	assert.StringEqual("first channel output filter", "export_v4", d.OutputFilter, t)
+
+	v4 := d.Channel("ipv4")
+	assert.StringEqual("ipv4 state", "UP", v4.State, t)
+	assert.StringEqual("ipv4 table", "master4", v4.Table, t)
+	assert.StringEqual("ipv4 input filter", "import_v4", v4.InputFilter, t)
+	assert.IntEqual("ipv4 imported", 650000, v4.Routes.Imported, t)
+	assert.IntEqual("ipv4 exported", 10, v4.Routes.Exported, t)
+	assert.IntEqual("ipv4 preferred", 640000, v4.Routes.Preferred, t)
+
+	v6 := d.Channel("ipv6")
+	assert.StringEqual("ipv6 table", "master6", v6.Table, t)
+	assert.StringEqual("ipv6 output filter", "export_v6", v6.OutputFilter, t)
+	assert.IntEqual("ipv6 preference", 110, v6.Preference, t)
+	assert.IntEqual("ipv6 imported", 120000, v6.Routes.Imported, t)
+	assert.IntEqual("ipv6 filtered", 4, v6.Routes.Filtered, t)
+	assert.IntEqual("ipv6 preferred", 110000, v6.Routes.Preferred, t)
+
+	static := protocols[2].Detail
+	assert.StringEqual("static state", "DOWN", static.Channel("ipv4").State, t)
+	assert.True("static without counters", static.Channel("ipv4").Routes == nil, t)
+	assert.True("missing channel", static.Channel("ipv6") == nil, t)
+	assert.True("no routes", static.Routes == nil, t)
+}
@@ -0,0 +1,13 @@
+package birdsocket
+
+// Querier is the subset of BirdSocket needed to send queries to Bird.
+// It allows code built on top of this package to replace the socket,
+// e.g. by a mock in tests
+type Querier interface {
+	Query(qry string, confirm bool) ([]byte, error)
+	QueryParsed(qry string) (*Reply, error)
+	Close()
+	IsConnected() bool
+}
+
+var _ Querier = (*BirdSocket)(nil)
@@ -0,0 +1,93 @@
+package birdsocket
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// mockQuerier answers queries from a static map of replies
+type mockQuerier struct {
+	replies map[string]string
+	queries []string
+	closed  bool
+}
+
+func (m *mockQuerier) Query(qry string, confirm bool) ([]byte, error) {
+	m.queries = append(m.queries, qry)
+
+	r, ok := m.replies[qry]
+	if !ok {
+		return nil, fmt.Errorf("unexpected query: %s", qry)
+	}
+
+	return []byte(r), nil
+}
+
+func (m *mockQuerier) QueryParsed(qry string) (*Reply, error) {
+	b, err := m.Query(qry, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseReply(b), nil
+}
+
+func (m *mockQuerier) Close() {
+	m.closed = true
+}
+
+func (m *mockQuerier) IsConnected() bool {
+	return !m.closed
+}
+
+// terminalMessage queries q and returns the message of the last line of the reply
+func terminalMessage(q Querier, qry string) (string, error) {
+	defer q.Close()
+
+	r, err := q.QueryParsed(qry)
+	if err != nil {
+		return "", err
+	}
+
+	t := r.terminal()
+	if t == nil {
+		return "", fmt.Errorf("incomplete reply to %s", qry)
+	}
+
+	return t.Message, nil
+}
+
+// TestQuerierMock verifies that code depending on Querier works with a mock
+func TestQuerierMock(t *testing.T) {
+	m := &mockQuerier{replies: map[string]string{
+		"show status": "1000-BIRD 2.0.7\n0013 Daemon is up and running\n",
+	}}
+
+	assert.True("connected", m.IsConnected(), t)
+
+	msg, err := terminalMessage(m, "show status")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("message", "Daemon is up and running", msg, t)
+	assert.IntEqual("queries", 1, len(m.queries), t)
+	assert.False("closed", m.IsConnected(), t)
+}
+
+// TestQuerierSocket verifies that BirdSocket can be used as Querier
+func TestQuerierSocket(t *testing.T) {
+	var q Querier = connectFakeBird(t, map[string]string{
+		"show status": "1000-BIRD 2.0.7\n0013 Daemon is up and running\n",
+	})
+
+	msg, err := terminalMessage(q, "show status")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("message", "Daemon is up and running", msg, t)
+	assert.False("closed", q.IsConnected(), t)
+}
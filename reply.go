@@ -0,0 +1,264 @@
+package birdsocket
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Line is a single line of a reply sent by Bird
+type Line struct {
+	// Code is the four-digit reply code. Lines without a code
+	// carry the code of the most recent coded line
+	Code int
+
+	// Continued is true if the line is followed by further
+	// lines belonging to the same reply
+	Continued bool
+
+	// Message is the textual part of the line
+	Message string
+}
+
+// Reply is a parsed reply sent by Bird
+type Reply struct {
+	Lines []Line
+}
+
+// QueryParsed sends an query to Bird and parses the reply
+func (s *BirdSocket) QueryParsed(qry string) (*Reply, error) {
+	b, err := s.Query(qry, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseReply(b), nil
+}
+
+// QueryChecked sends an query to Bird and parses the reply.
+// If Bird replies with a run-time or parse-time error, the reply
+// is returned together with a *BirdError
+func (s *BirdSocket) QueryChecked(qry string) (*Reply, error) {
+	r, err := s.QueryParsed(qry)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, replyError(r)
+}
+
+// QueryMinLines sends an query to Bird and parses the reply like QueryChecked.
+// If the reply is complete but has less than n lines carrying a reply code
+// (including the final status line), the reply is returned together with an error
+// matching ErrIncompleteReply, e.g. to detect a truncated reply of a command
+// known to always list at least n entries
+func (s *BirdSocket) QueryMinLines(qry string, n int) (*Reply, error) {
+	b, err := s.Query(qry, true)
+	if err != nil {
+		return nil, err
+	}
+
+	r := ParseReply(b)
+	if err := replyError(r); err != nil {
+		return r, err
+	}
+
+	coded := 0
+	for _, l := range bytes.Split(b, []byte("\n")) {
+		if _, _, ok := parseCode(l); ok {
+			coded++
+		}
+	}
+
+	if coded < n {
+		return r, fmt.Errorf("%w: %d of at least %d lines received", ErrIncompleteReply, coded, n)
+	}
+
+	return r, nil
+}
+
+// QueryLines sends an query to Bird and returns the textual part of every reply line.
+// Blank lines and the final status line are omitted. If Bird replies with
+// a run-time or parse-time error, a *BirdError is returned instead
+func (s *BirdSocket) QueryLines(qry string) ([]string, error) {
+	r, err := s.QueryChecked(qry)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0, len(r.Lines))
+	for _, l := range r.Lines {
+		if !l.Continued || len(strings.TrimSpace(l.Message)) == 0 {
+			continue
+		}
+
+		lines = append(lines, l.Message)
+	}
+
+	return lines, nil
+}
+
+// QueryScan sends an query to Bird and passes the textual part of every reply line
+// (including the final status line) to parse, e.g. for commands without a parser in this package.
+// If Bird replies with a run-time or parse-time error, a *BirdError is returned without calling parse
+func (s *BirdSocket) QueryScan(qry string, parse func(lines []string) error) error {
+	r, err := s.QueryChecked(qry)
+	if err != nil {
+		return err
+	}
+
+	lines := make([]string, len(r.Lines))
+	for i, l := range r.Lines {
+		lines[i] = l.Message
+	}
+
+	return parse(lines)
+}
+
+// Block is a line carrying a reply code together with the following lines without a code
+type Block struct {
+	Code  int
+	Lines []string
+}
+
+// QueryBlocks sends an query to Bird and splits the reply into blocks, each one
+// starting with a line carrying a reply code (e.g. a protocol in `show protocols all`
+// followed by a block of its details). The final status line is omitted.
+// If Bird replies with a run-time or parse-time error, a *BirdError is returned instead
+func (s *BirdSocket) QueryBlocks(qry string) ([]Block, error) {
+	b, err := s.Query(qry, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := replyError(ParseReply(b)); err != nil {
+		return nil, err
+	}
+
+	return parseBlocks(b), nil
+}
+
+func parseBlocks(b []byte) []Block {
+	blocks := make([]Block, 0)
+
+	for _, l := range bytes.Split(b, []byte("\n")) {
+		if len(l) == 0 {
+			continue
+		}
+
+		code, continued, ok := parseCode(l)
+		switch {
+		case ok && !continued:
+			return blocks
+		case ok:
+			blocks = append(blocks, Block{Code: code, Lines: []string{message(l)}})
+		case len(blocks) > 0:
+			blk := &blocks[len(blocks)-1]
+			blk.Lines = append(blk.Lines, string(bytes.TrimPrefix(l, []byte(" "))))
+		}
+	}
+
+	return blocks
+}
+
+// ParseReply parses the raw output received from Bird
+func ParseReply(b []byte) *Reply {
+	r := &Reply{Lines: make([]Line, 0)}
+	code := 0
+
+	for _, l := range bytes.Split(b, []byte("\n")) {
+		if len(l) == 0 {
+			continue
+		}
+
+		if c, continued, ok := parseCode(l); ok {
+			code = c
+			r.Lines = append(r.Lines, Line{Code: c, Continued: continued, Message: message(l)})
+			continue
+		}
+
+		// lines without a code are continuations of the most recent coded line,
+		// Bird separates them from the code column by a single space
+		r.Lines = append(r.Lines, Line{Code: code, Continued: true, Message: string(bytes.TrimPrefix(l, []byte(" ")))})
+	}
+
+	return r
+}
+
+// QueryClean sends an query to Bird and returns the reply without reply codes
+func (s *BirdSocket) QueryClean(qry string) ([]byte, error) {
+	b, err := s.Query(qry, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return CleanOutput(b), nil
+}
+
+// CleanOutput removes the reply codes from coded lines and the single space
+// Bird prepends to continuation lines, everything else is preserved.
+// Four digits at the beginning of a line are only considered a reply code
+// if they are followed by a space, a minus sign or the end of the line
+func CleanOutput(raw []byte) []byte {
+	out := make([]byte, 0, len(raw))
+
+	for len(raw) > 0 {
+		var l []byte
+		if i := bytes.IndexByte(raw, '\n'); i >= 0 {
+			l, raw = raw[:i+1], raw[i+1:]
+		} else {
+			l, raw = raw, nil
+		}
+
+		if _, _, ok := parseCode(bytes.TrimSuffix(l, []byte("\n"))); ok {
+			if len(l) > 4 && l[4] != '\n' {
+				l = l[5:]
+			} else {
+				l = l[4:]
+			}
+		} else {
+			l = bytes.TrimPrefix(l, []byte(" "))
+		}
+
+		out = append(out, l...)
+	}
+
+	return out
+}
+
+// terminal returns the final line of the reply or nil if the reply is incomplete
+func (r *Reply) terminal() *Line {
+	if len(r.Lines) == 0 || r.Lines[len(r.Lines)-1].Continued {
+		return nil
+	}
+
+	return &r.Lines[len(r.Lines)-1]
+}
+
+// parseCode parses the reply code at the beginning of line l. A code consists of
+// four digits at the very beginning of the line followed by a space, a minus sign or nothing.
+// Bird indents lines without a code, so digits in route data (e.g. `1000::/3`
+// or an AS path) are never taken for a code
+func parseCode(l []byte) (code int, continued bool, ok bool) {
+	if len(l) < 4 || (len(l) > 4 && l[4] != ' ' && l[4] != '-') {
+		return 0, false, false
+	}
+
+	for _, d := range l[:4] {
+		if d < '0' || d > '9' {
+			return 0, false, false
+		}
+		code = code*10 + int(d-'0')
+	}
+
+	return code, len(l) > 4 && l[4] == '-', true
+}
+
+// message returns the textual part of coded line l
+func message(l []byte) string {
+	if len(l) <= 5 {
+		return ""
+	}
+
+	return string(l[5:])
+}
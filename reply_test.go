@@ -0,0 +1,297 @@
+package birdsocket
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestParseReplyShowStatus verifies parsing of a multi-line
+// reply including lines without a leading code
+func TestParseReplyShowStatus(t *testing.T) {
+	out := "1000-BIRD 1.6.4\n" +
+		"1011-Router ID is 192.168.1.9\n" +
+		" Current server time is 2018-12-27 12:15:01\n" +
+		" Last reboot on 2018-12-21 12:35:11\n" +
+		"0013 Daemon is up and running\n"
+	r := ParseReply([]byte(out))
+
+	assert.IntEqual("lines", 5, len(r.Lines), t)
+
+	assert.IntEqual("line 0 code", 1000, r.Lines[0].Code, t)
+	assert.True("line 0 continued", r.Lines[0].Continued, t)
+	assert.StringEqual("line 0 message", "BIRD 1.6.4", r.Lines[0].Message, t)
+
+	assert.IntEqual("line 2 code", 1011, r.Lines[2].Code, t)
+	assert.True("line 2 continued", r.Lines[2].Continued, t)
+	assert.StringEqual("line 2 message", "Current server time is 2018-12-27 12:15:01", r.Lines[2].Message, t)
+
+	assert.IntEqual("line 4 code", 13, r.Lines[4].Code, t)
+	assert.False("line 4 continued", r.Lines[4].Continued, t)
+	assert.StringEqual("line 4 message", "Daemon is up and running", r.Lines[4].Message, t)
+}
+
+// TestParseReplyCodeOnly verifies parsing of a final line
+// consisting of the code only
+func TestParseReplyCodeOnly(t *testing.T) {
+	r := ParseReply([]byte("1002-device1  Device   master   up\n0000\n"))
+
+	assert.IntEqual("lines", 2, len(r.Lines), t)
+	assert.IntEqual("code", 0, r.Lines[1].Code, t)
+	assert.False("continued", r.Lines[1].Continued, t)
+	assert.StringEqual("message", "", r.Lines[1].Message, t)
+}
+
+// TestQueryParsed verifies that a query reply is returned parsed
+func TestQueryParsed(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show status": "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+	})
+
+	r, err := s.QueryParsed("show status")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("lines", 2, len(r.Lines), t)
+	assert.StringEqual("version", "BIRD 1.6.4", r.Lines[0].Message, t)
+}
+
+// TestQueryLines verifies that reply codes, blank lines and
+// the final status line are stripped from the reply
+func TestQueryLines(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show status": "1000-BIRD 1.6.4\n" +
+			"1011-Router ID is 192.168.1.9\n" +
+			" Current server time is 2018-12-27 12:15:01\n" +
+			" \n" +
+			"0013 Daemon is up and running\n",
+	})
+
+	lines, err := s.QueryLines("show status")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("lines", 3, len(lines), t)
+	assert.StringEqual("line 0", "BIRD 1.6.4", lines[0], t)
+	assert.StringEqual("line 1", "Router ID is 192.168.1.9", lines[1], t)
+	assert.StringEqual("line 2", "Current server time is 2018-12-27 12:15:01", lines[2], t)
+}
+
+// TestQueryLinesError verifies that an error reply is returned as error
+func TestQueryLinesError(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{})
+
+	lines, err := s.QueryLines("show foo")
+
+	_, ok := err.(*BirdError)
+	assert.True("*BirdError returned", ok, t)
+	assert.True("no lines returned", lines == nil, t)
+}
+
+// TestQueryCheckedSyntaxError verifies that a parse-time
+// error reply is returned as *BirdError
+func TestQueryCheckedSyntaxError(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{})
+
+	_, err := s.QueryChecked("show foo")
+
+	birdErr, ok := err.(*BirdError)
+	assert.True("*BirdError returned", ok, t)
+	assert.IntEqual("code", CodeSyntaxError, birdErr.Code, t)
+	assert.StringEqual("message", "syntax error", birdErr.Message, t)
+}
+
+// TestQueryCheckedOK verifies that no error is returned
+// for a successfully completed query
+func TestQueryCheckedOK(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show protocols": "2002-name     proto    table    state  since       info\n" +
+			"1002-device1  Device   master   up     2018-12-21 12:35:11\n" +
+			"0000 \n",
+	})
+
+	r, err := s.QueryChecked("show protocols")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("lines", 3, len(r.Lines), t)
+}
+
+// TestCleanOutput verifies that reply codes are removed from
+// coded lines and continuation lines
+func TestCleanOutput(t *testing.T) {
+	out := "1007-2001:db8::/32        unreachable [static1 2018-12-21] * (200)\n" +
+		" 1000::/3                 unreachable [static1 2018-12-21] * (200)\n" +
+		"2001:db8:1::/48 via fe80::1 on eth0\n" +
+		"1008-\tType: static univ\n" +
+		"0000 \n"
+	expected := "2001:db8::/32        unreachable [static1 2018-12-21] * (200)\n" +
+		"1000::/3                 unreachable [static1 2018-12-21] * (200)\n" +
+		"2001:db8:1::/48 via fe80::1 on eth0\n" +
+		"\tType: static univ\n" +
+		"\n"
+
+	assert.StringEqual("output", expected, string(CleanOutput([]byte(out))), t)
+}
+
+// TestQueryClean verifies that the reply of a query is returned without reply codes
+func TestQueryClean(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show status": "1000-BIRD 1.6.4\n" +
+			"1011-Router ID is 192.168.1.9\n" +
+			" Current server time is 2018-12-27 12:15:01\n" +
+			"0013 Daemon is up and running\n",
+	})
+
+	out, err := s.QueryClean("show status")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "BIRD 1.6.4\n" +
+		"Router ID is 192.168.1.9\n" +
+		"Current server time is 2018-12-27 12:15:01\n" +
+		"Daemon is up and running\n"
+	assert.StringEqual("output", expected, string(out), t)
+}
+
+// TestQueryScan verifies parsing the reply of a command using a custom parser
+func TestQueryScan(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show roa": "1019-10.0.0.0/8 max 24 as 65001\n" +
+			" 192.168.0.0/16 max 16 as 65002\n" +
+			"0000 \n",
+	})
+
+	type roa struct {
+		prefix string
+		asn    int
+	}
+	roas := make([]roa, 0)
+
+	err := s.QueryScan("show roa", func(lines []string) error {
+		for _, l := range lines {
+			var r roa
+			var maxLen int
+			if _, err := fmt.Sscanf(l, "%s max %d as %d", &r.prefix, &maxLen, &r.asn); err != nil {
+				continue
+			}
+			roas = append(roas, r)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("roas", 2, len(roas), t)
+	assert.StringEqual("prefix", "192.168.0.0/16", roas[1].prefix, t)
+	assert.IntEqual("asn", 65002, roas[1].asn, t)
+
+	called := false
+	err = s.QueryScan("show foo", func(lines []string) error {
+		called = true
+		return nil
+	})
+	_, ok := err.(*BirdError)
+	assert.True("*BirdError returned", ok, t)
+	assert.False("parse called", called, t)
+}
+
+// TestLastCode verifies the code of the final line after successful and failed queries
+func TestLastCode(t *testing.T) {
+	s := NewSocket("", WithReadDeadline(100*time.Millisecond), WithDialer(pipeDialer(serveReplies(map[string]string{
+		"show status":    "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+		"show protocols": "0000 \n",
+		"show stalled":   "1002-device1  Device   master   up\n",
+	}))))
+	assert.IntEqual("before connect", -1, s.LastCode(), t)
+
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	assert.IntEqual("welcome", CodeWelcome, s.LastCode(), t)
+
+	tests := []struct {
+		qry  string
+		code int
+	}{
+		{qry: "show status", code: CodeStatusReport},
+		{qry: "show protocols", code: CodeReplyOK},
+		{qry: "show foo", code: CodeSyntaxError},
+		{qry: "show stalled", code: -1},
+	}
+
+	for _, test := range tests {
+		s.Query(test.qry, true)
+		assert.IntEqual(test.qry, test.code, s.LastCode(), t)
+	}
+}
+
+// TestQueryBlocks verifies the blocks of a `show protocols all` reply listing two protocols
+func TestQueryBlocks(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show protocols all": "1002-device1  Device   master   up     2018-12-21 12:35:11\n" +
+			"1006-  Preference:     240\n" +
+			"  Input filter:   ACCEPT\n" +
+			"  Output filter:  REJECT\n" +
+			"\n" +
+			"1002-upstream1 BGP     master   up     2018-12-21 12:35:11  Established\n" +
+			"1006-  Description:    Upstream provider\n" +
+			"  Preference:     100\n" +
+			"\n" +
+			"0000 \n",
+	})
+
+	blocks, err := s.QueryBlocks("show protocols all")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	protocols := make([]Block, 0)
+	for _, b := range blocks {
+		if b.Code == CodeProtocolList {
+			protocols = append(protocols, b)
+		}
+	}
+
+	assert.IntEqual("blocks", 4, len(blocks), t)
+	assert.IntEqual("protocol blocks", 2, len(protocols), t)
+	assert.StringEqual("protocol", "upstream1 BGP     master   up     2018-12-21 12:35:11  Established", protocols[1].Lines[0], t)
+
+	details := blocks[3]
+	assert.IntEqual("details code", CodeProtocolDetails, details.Code, t)
+	assert.IntEqual("detail lines", 2, len(details.Lines), t)
+	assert.StringEqual("detail", " Preference:     100", details.Lines[1], t)
+
+	_, err = s.QueryBlocks("show foo")
+	_, ok := err.(*BirdError)
+	assert.True("*BirdError returned", ok, t)
+}
+
+// TestQueryMinLines verifies that a reply with less lines than expected is detected
+func TestQueryMinLines(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show protocols": "2002-name     proto    table    state  since       info\n" +
+			"1002-bgp1     BGP      master   up     2018-12-21  Established\n" +
+			"0000 \n",
+	})
+
+	r, err := s.QueryMinLines("show protocols", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.IntEqual("lines", 3, len(r.Lines), t)
+
+	r, err = s.QueryMinLines("show protocols", 4)
+	assert.True("ErrIncompleteReply", errors.Is(err, ErrIncompleteReply), t)
+	assert.True("reply returned", r != nil, t)
+}
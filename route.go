@@ -0,0 +1,476 @@
+package birdsocket
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var routeAttributesRegex *regexp.Regexp
+var routeAttributeLineRegex *regexp.Regexp
+var communityRegex *regexp.Regexp
+
+func init() {
+	// e.g. `via 192.168.1.1 on eth0 [bgp1 2018-12-21 from 192.168.1.1] * (100/10) [AS65001i]`
+	routeAttributesRegex = regexp.MustCompile(`^(.*?)\s*\[(\S+)(?:\s+([^\]]*?))?(?:\s+from\s+(\S+))?\]\s*(\*)?\s*(?:\((\d+)(?:/(\d+|\?))?\))?\s*(?:\[(?:AS(\d+))?([ie?])\])?`)
+	routeAttributeLineRegex = regexp.MustCompile(`^[\w.]+:`)
+	communityRegex = regexp.MustCompile(`\(([^)]*)\)`)
+}
+
+// Route is a route as listed by `show route`.
+// Type is the kind of route like unicast, blackhole or unreachable
+// (BIRD 1.x omits it for routes via a gateway), Origin is the BGP origin
+// as abbreviated in the route line (i, e or ?)
+type Route struct {
+	Table      string
+	Network    string
+	Type       string
+	Gateway    string
+	Interface  string
+	NextHops   []NextHop
+	Protocol   string
+	Since      time.Time
+	From       string
+	Primary    bool
+	Preference int
+	Metric     int
+	OriginAS   int
+	Origin     string
+
+	// The following fields are only set when querying with All()
+	ASPath     []string
+	BGPNextHop string
+	LocalPref  int
+	MED        int
+
+	// Communities are (ASN, value), large communities (ASN, data1, data2) and
+	// extended communities as listed by Bird without the parentheses, e.g. `rt, 65000, 1`
+	Communities      [][2]int
+	LargeCommunities [][3]int
+	ExtCommunities   []string
+
+	// Attributes holds all attributes listed in the verbose output keyed by their name
+	Attributes map[string]string
+
+	// FilterResult is the outcome of the filters for routes queried by Export, NoExport
+	// or Filtered: FilterAccepted or FilterRejected. Bird does not print the outcome itself,
+	// so it is derived from the query and empty for all other queries
+	FilterResult string
+}
+
+const (
+	// FilterAccepted is the FilterResult of routes accepted by the filter
+	FilterAccepted = "accepted"
+
+	// FilterRejected is the FilterResult of routes rejected by the filter
+	FilterRejected = "rejected"
+)
+
+// NextHop is a next hop of a route
+type NextHop struct {
+	Gateway   string
+	Interface string
+	Weight    int
+}
+
+// RouteOption modifies the `show route` command
+type RouteOption func(*routeQuery)
+
+type routeQuery struct {
+	prefix   string
+	table    string
+	protocol string
+	primary  bool
+	filtered bool
+	all      bool
+	count    bool
+
+	// view is the keyword selecting the routes as seen by a filter of viewProtocol,
+	// e.g. export or import table
+	view         string
+	viewProtocol string
+}
+
+// ForPrefix limits the routes to the best matching network of cidr (or IP address).
+// Querying fails without sending the command if cidr is neither a valid IPv4 nor IPv6 prefix or address
+func ForPrefix(cidr string) RouteOption {
+	return func(q *routeQuery) {
+		q.prefix = cidr
+	}
+}
+
+// Table limits the routes to the table name
+func Table(name string) RouteOption {
+	return func(q *routeQuery) {
+		q.table = name
+	}
+}
+
+// ForProtocol limits the routes to the ones learned by protocol name
+func ForProtocol(name string) RouteOption {
+	return func(q *routeQuery) {
+		q.protocol = name
+	}
+}
+
+// Primary limits the routes to the primary ones
+func Primary() RouteOption {
+	return func(q *routeQuery) {
+		q.primary = true
+	}
+}
+
+// Filtered lists the routes rejected by import filters instead of the accepted ones
+func Filtered() RouteOption {
+	return func(q *routeQuery) {
+		q.filtered = true
+	}
+}
+
+// Export lists the routes accepted by the export filter of protocol name,
+// i.e. the routes exported to it
+func Export(name string) RouteOption {
+	return viewRoutes("export", name)
+}
+
+// NoExport lists the routes rejected by the export filter of protocol name
+func NoExport(name string) RouteOption {
+	return viewRoutes("noexport", name)
+}
+
+// PreExport lists the routes offered to protocol name before applying its export filter
+func PreExport(name string) RouteOption {
+	return viewRoutes("preexport", name)
+}
+
+// PreImport lists the routes received by protocol name before applying its import filter.
+// Bird keeps these routes only if `import table` is enabled for the protocol (or its channel)
+func PreImport(name string) RouteOption {
+	return viewRoutes("import table", name)
+}
+
+func viewRoutes(view, name string) RouteOption {
+	return func(q *routeQuery) {
+		q.view = view
+		q.viewProtocol = name
+	}
+}
+
+// All includes all attributes of the routes
+func All() RouteOption {
+	return func(q *routeQuery) {
+		q.all = true
+	}
+}
+
+func newRouteQuery(opts []RouteOption) *routeQuery {
+	q := &routeQuery{}
+	for _, o := range opts {
+		o(q)
+	}
+
+	return q
+}
+
+// validate returns an error if the query would be rejected by Bird
+func (q *routeQuery) validate() error {
+	if q.view != "" && !plainArgRegex.MatchString(q.viewProtocol) {
+		return fmt.Errorf("invalid protocol %q", q.viewProtocol)
+	}
+
+	if q.prefix == "" {
+		return nil
+	}
+
+	if _, err := netip.ParsePrefix(q.prefix); err == nil {
+		return nil
+	}
+
+	if addr, err := netip.ParseAddr(q.prefix); err != nil || addr.Zone() != "" {
+		return fmt.Errorf("invalid prefix %q: neither a network in CIDR notation nor an IP address", q.prefix)
+	}
+
+	return nil
+}
+
+func (q *routeQuery) String() string {
+	parts := []string{"show route"}
+	if q.prefix != "" {
+		parts = append(parts, "for", q.prefix)
+	}
+
+	if q.table != "" {
+		parts = append(parts, "table", q.table)
+	}
+
+	if q.protocol != "" {
+		parts = append(parts, "protocol", q.protocol)
+	}
+
+	if q.primary {
+		parts = append(parts, "primary")
+	}
+
+	if q.filtered {
+		parts = append(parts, "filtered")
+	}
+
+	if q.view != "" {
+		parts = append(parts, q.view, q.viewProtocol)
+	}
+
+	if q.all {
+		parts = append(parts, "all")
+	}
+
+	if q.count {
+		parts = append(parts, "count")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// ShowRoute queries the routes matching the options
+func (s *BirdSocket) ShowRoute(opts ...RouteOption) ([]Route, error) {
+	q := newRouteQuery(opts)
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+
+	r, err := s.QueryChecked(q.String())
+	if err != nil {
+		if birdErr, ok := err.(*BirdError); ok && birdErr.Code == CodeRouteNotFound {
+			return []Route{}, nil
+		}
+
+		return nil, err
+	}
+
+	routes := parseRoutes(r)
+	if result := q.filterResult(); result != "" {
+		for i := range routes {
+			routes[i].FilterResult = result
+		}
+	}
+
+	return routes, nil
+}
+
+// filterResult returns the outcome of the filters for all routes matching the query (if known)
+func (q *routeQuery) filterResult() string {
+	switch {
+	case q.view == "export":
+		return FilterAccepted
+	case q.view == "noexport" || q.filtered:
+		return FilterRejected
+	default:
+		return ""
+	}
+}
+
+// RouteCount queries the number of routes matching the options
+// without transferring the routes themselves
+func (s *BirdSocket) RouteCount(opts ...RouteOption) (int, error) {
+	q := newRouteQuery(opts)
+	q.count = true
+	if err := q.validate(); err != nil {
+		return 0, err
+	}
+
+	r, err := s.QueryChecked(q.String())
+	if err != nil {
+		if birdErr, ok := err.(*BirdError); ok && birdErr.Code == CodeRouteNotFound {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	t := r.terminal()
+	if t == nil || t.Code != CodeRouteCount {
+		return 0, errors.New("unexpected reply to route count")
+	}
+
+	// e.g. `10 of 12 routes for 8 networks` (Bird 1.6) or
+	// `Total: 10 of 12 routes for 8 networks in 1 tables` (Bird 2.0)
+	f := strings.Fields(strings.TrimPrefix(t.Message, "Total:"))
+	if len(f) == 0 {
+		return 0, fmt.Errorf("invalid route count: %q", t.Message)
+	}
+
+	n, err := strconv.Atoi(f[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid route count: %q", t.Message)
+	}
+
+	return n, nil
+}
+
+func parseRoutes(r *Reply) []Route {
+	routes := make([]Route, 0)
+	table := ""
+	network := ""
+
+	for _, l := range r.Lines {
+		if !l.Continued {
+			continue
+		}
+
+		msg := l.Message
+		trimmed := strings.TrimSpace(msg)
+		if len(trimmed) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(msg, "Table ") && strings.HasSuffix(trimmed, ":") {
+			table = strings.TrimSuffix(strings.TrimPrefix(trimmed, "Table "), ":")
+			continue
+		}
+
+		indented := msg[0] == ' ' || msg[0] == '\t'
+		if !indented {
+			f := strings.Fields(msg)
+			network = f[0]
+			routes = append(routes, parseRouteLine(table, network, strings.TrimSpace(msg[len(f[0]):])))
+			continue
+		}
+
+		if len(routes) == 0 {
+			continue
+		}
+		rt := &routes[len(routes)-1]
+
+		switch {
+		case routeAttributeLineRegex.MatchString(trimmed):
+			rt.addAttribute(splitKeyValue(trimmed))
+		case strings.Contains(trimmed, "["):
+			// alternative route for the same network
+			routes = append(routes, parseRouteLine(table, network, trimmed))
+		case strings.HasPrefix(trimmed, "via ") || strings.HasPrefix(trimmed, "dev "):
+			rt.addNextHop(parseNextHop(trimmed))
+		}
+	}
+
+	return routes
+}
+
+// parseRouteLine parses the attributes following the network in a route line
+func parseRouteLine(table, network, s string) Route {
+	rt := Route{Table: table, Network: network, NextHops: make([]NextHop, 0), Attributes: make(map[string]string)}
+
+	m := routeAttributesRegex.FindStringSubmatch(s)
+	if m == nil {
+		rt.Type = s
+		return rt
+	}
+
+	switch nh := parseNextHop(m[1]); {
+	case nh.Gateway != "" || nh.Interface != "":
+		rt.addNextHop(nh)
+	default:
+		rt.Type = m[1]
+	}
+
+	rt.Protocol = m[2]
+	rt.Since, _ = parseSince(strings.Fields(m[3]))
+	rt.From = m[4]
+	rt.Primary = m[5] == "*"
+	rt.Preference, _ = strconv.Atoi(m[6])
+	rt.Metric, _ = strconv.Atoi(m[7])
+	rt.OriginAS, _ = strconv.Atoi(m[8])
+	rt.Origin = m[9]
+
+	return rt
+}
+
+// parseNextHop parses next hops like `via 192.168.1.1 on eth0 weight 1` or `dev eth0`
+func parseNextHop(s string) NextHop {
+	nh := NextHop{}
+
+	f := strings.Fields(s)
+	for i := 0; i+1 < len(f); i += 2 {
+		switch f[i] {
+		case "via":
+			nh.Gateway = f[i+1]
+		case "on", "dev":
+			nh.Interface = f[i+1]
+		case "weight":
+			nh.Weight, _ = strconv.Atoi(f[i+1])
+		}
+	}
+
+	return nh
+}
+
+func (rt *Route) addNextHop(nh NextHop) {
+	if len(rt.NextHops) == 0 {
+		rt.Gateway = nh.Gateway
+		rt.Interface = nh.Interface
+	}
+
+	rt.NextHops = append(rt.NextHops, nh)
+}
+
+func (rt *Route) addAttribute(key, value string) {
+	rt.Attributes[key] = value
+
+	switch key {
+	case "BGP.as_path":
+		rt.ASPath = strings.Fields(value)
+	case "BGP.next_hop":
+		rt.BGPNextHop = value
+	case "BGP.local_pref":
+		rt.LocalPref, _ = strconv.Atoi(value)
+	case "BGP.med":
+		rt.MED, _ = strconv.Atoi(value)
+	case "BGP.community":
+		for _, c := range parseCommunities(value) {
+			if v, ok := parseCommunityValues(c, 2); ok {
+				rt.Communities = append(rt.Communities, [2]int{v[0], v[1]})
+			}
+		}
+	case "BGP.large_community":
+		for _, c := range parseCommunities(value) {
+			if v, ok := parseCommunityValues(c, 3); ok {
+				rt.LargeCommunities = append(rt.LargeCommunities, [3]int{v[0], v[1], v[2]})
+			}
+		}
+	case "BGP.ext_community":
+		rt.ExtCommunities = append(rt.ExtCommunities, parseCommunities(value)...)
+	}
+}
+
+// parseCommunities returns the content of the parenthesized communities in s,
+// e.g. `(65000,1) (65000,2)`
+func parseCommunities(s string) []string {
+	m := communityRegex.FindAllStringSubmatch(s, -1)
+	communities := make([]string, len(m))
+	for i, c := range m {
+		communities[i] = strings.TrimSpace(c[1])
+	}
+
+	return communities
+}
+
+// parseCommunityValues parses the n comma separated numbers of a community like `65000, 1, 2`
+func parseCommunityValues(s string, n int) ([]int, bool) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, false
+	}
+
+	values := make([]int, n)
+	for i, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, false
+		}
+		values[i] = v
+	}
+
+	return values, true
+}
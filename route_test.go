@@ -0,0 +1,372 @@
+package birdsocket
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestRouteQuery verifies the command built from the options
+func TestRouteQuery(t *testing.T) {
+	q := newRouteQuery([]RouteOption{ForPrefix("10.0.0.0/8"), Table("master4"), ForProtocol("bgp1"), Primary(), Filtered(), All()})
+
+	assert.StringEqual("query", "show route for 10.0.0.0/8 table master4 protocol bgp1 primary filtered all", q.String(), t)
+}
+
+// TestShowRouteV1 verifies parsing of `show route` as sent by BIRD 1.6
+func TestShowRouteV1(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show route": "1007-10.0.0.0/8          via 192.168.1.1 on eth0 [static1 2018-12-21] * (200)\n" +
+			" 10.1.0.0/16        via 192.168.1.2 on eth0 [bgp1 2018-12-21 from 192.168.1.2] * (100/10) [AS65001i]\n" +
+			"                    via 192.168.1.3 on eth1 [bgp2 2018-12-21] (100/20) [AS65002e]\n" +
+			" 192.168.0.0/16     dev eth1 [direct1 2018-12-21] * (240)\n" +
+			" 10.2.0.0/16        multipath [static2 12:00:00] * (200)\n" +
+			" \tvia 192.168.1.1 on eth0 weight 1\n" +
+			" \tvia 192.168.1.2 on eth1 weight 2\n" +
+			"0000 \n",
+	})
+
+	routes, err := s.ShowRoute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("routes", 5, len(routes), t)
+
+	r := routes[0]
+	assert.StringEqual("network", "10.0.0.0/8", r.Network, t)
+	assert.StringEqual("gateway", "192.168.1.1", r.Gateway, t)
+	assert.StringEqual("interface", "eth0", r.Interface, t)
+	assert.StringEqual("protocol", "static1", r.Protocol, t)
+	assert.True("since", r.Since.Equal(time.Date(2018, 12, 21, 0, 0, 0, 0, time.Local)), t)
+	assert.True("primary", r.Primary, t)
+	assert.IntEqual("preference", 200, r.Preference, t)
+
+	r = routes[1]
+	assert.StringEqual("network", "10.1.0.0/16", r.Network, t)
+	assert.StringEqual("from", "192.168.1.2", r.From, t)
+	assert.IntEqual("metric", 10, r.Metric, t)
+	assert.IntEqual("origin as", 65001, r.OriginAS, t)
+	assert.StringEqual("origin", "i", r.Origin, t)
+
+	r = routes[2]
+	assert.StringEqual("network", "10.1.0.0/16", r.Network, t)
+	assert.StringEqual("gateway", "192.168.1.3", r.Gateway, t)
+	assert.StringEqual("protocol", "bgp2", r.Protocol, t)
+	assert.False("primary", r.Primary, t)
+	assert.IntEqual("origin as", 65002, r.OriginAS, t)
+
+	r = routes[3]
+	assert.StringEqual("gateway", "", r.Gateway, t)
+	assert.StringEqual("interface", "eth1", r.Interface, t)
+
+	r = routes[4]
+	assert.StringEqual("type", "multipath", r.Type, t)
+	assert.IntEqual("next hops", 2, len(r.NextHops), t)
+	assert.StringEqual("next hop 1 gateway", "192.168.1.2", r.NextHops[1].Gateway, t)
+	assert.StringEqual("next hop 1 interface", "eth1", r.NextHops[1].Interface, t)
+	assert.IntEqual("next hop 1 weight", 2, r.NextHops[1].Weight, t)
+}
+
+// TestShowRouteV2 verifies parsing of `show route` for IPv4 and IPv6 tables as sent by BIRD 2.0
+func TestShowRouteV2(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show route": "1007-Table master4:\n" +
+			" 10.0.0.0/8           unicast [static1 2021-02-28] * (200)\n" +
+			" \tvia 192.168.1.1 on eth0\n" +
+			" 10.2.0.0/16          unicast [static2 2021-02-28 09:00:00] * (200)\n" +
+			" \tvia 192.168.1.1 on eth0 weight 1\n" +
+			" \tvia 192.168.1.2 on eth1 weight 1\n" +
+			" 10.3.0.0/16          blackhole [static3 2021-02-28] * (200)\n" +
+			" \n" +
+			" Table master6:\n" +
+			" 2001:db8::/32        unicast [bgp1 2021-02-28 from 2001:db8:ffff::1] * (100) [AS65001i]\n" +
+			" \tvia 2001:db8:ffff::1 on eth0\n" +
+			"                      unicast [bgp2 2021-02-28] (100) [AS65002?]\n" +
+			" \tvia fe80::1 on eth1\n" +
+			"0000 \n",
+	})
+
+	routes, err := s.ShowRoute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("routes", 5, len(routes), t)
+
+	r := routes[0]
+	assert.StringEqual("table", "master4", r.Table, t)
+	assert.StringEqual("type", "unicast", r.Type, t)
+	assert.StringEqual("gateway", "192.168.1.1", r.Gateway, t)
+	assert.StringEqual("interface", "eth0", r.Interface, t)
+
+	r = routes[1]
+	assert.True("since", r.Since.Equal(time.Date(2021, 2, 28, 9, 0, 0, 0, time.Local)), t)
+	assert.IntEqual("next hops", 2, len(r.NextHops), t)
+
+	r = routes[2]
+	assert.StringEqual("type", "blackhole", r.Type, t)
+	assert.IntEqual("next hops", 0, len(r.NextHops), t)
+
+	r = routes[3]
+	assert.StringEqual("table", "master6", r.Table, t)
+	assert.StringEqual("network", "2001:db8::/32", r.Network, t)
+	assert.StringEqual("from", "2001:db8:ffff::1", r.From, t)
+	assert.StringEqual("gateway", "2001:db8:ffff::1", r.Gateway, t)
+
+	r = routes[4]
+	assert.StringEqual("network", "2001:db8::/32", r.Network, t)
+	assert.StringEqual("protocol", "bgp2", r.Protocol, t)
+	assert.StringEqual("gateway", "fe80::1", r.Gateway, t)
+	assert.StringEqual("interface", "eth1", r.Interface, t)
+	assert.StringEqual("origin", "?", r.Origin, t)
+}
+
+// TestShowRouteAll verifies parsing of the attributes listed by `show route all`
+func TestShowRouteAll(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show route for 10.1.0.0/16 all": "1007-10.1.0.0/16        via 192.168.1.2 on eth0 [bgp1 2018-12-21 from 192.168.1.2] * (100) [AS65002i]\n" +
+			"1008-\tType: BGP unicast univ\n" +
+			"1012-\tBGP.origin: IGP\n" +
+			" \tBGP.as_path: 65001 65002\n" +
+			" \tBGP.next_hop: 192.168.1.2\n" +
+			" \tBGP.med: 50\n" +
+			" \tBGP.local_pref: 100\n" +
+			"0000 \n",
+		"show route for 2001:db8::/32 all": "1007-Table master6:\n" +
+			" 2001:db8::/32        unicast [bgp1 2021-02-28] * (100) [AS65001i]\n" +
+			" \tvia 2001:db8:ffff::1 on eth0\n" +
+			"1008-\tType: BGP univ\n" +
+			"1012-\tBGP.origin: IGP\n" +
+			" \tBGP.as_path: 65001\n" +
+			" \tBGP.next_hop: 2001:db8:ffff::1 fe80::1\n" +
+			" \tBGP.local_pref: 100\n" +
+			"0000 \n",
+	})
+
+	routes, err := s.ShowRoute(ForPrefix("10.1.0.0/16"), All())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("routes", 1, len(routes), t)
+
+	r := routes[0]
+	assert.StringEqual("as path", "65001 65002", strings.Join(r.ASPath, " "), t)
+	assert.StringEqual("bgp next hop", "192.168.1.2", r.BGPNextHop, t)
+	assert.IntEqual("local pref", 100, r.LocalPref, t)
+	assert.IntEqual("med", 50, r.MED, t)
+	assert.StringEqual("type attribute", "BGP unicast univ", r.Attributes["Type"], t)
+	assert.StringEqual("origin attribute", "IGP", r.Attributes["BGP.origin"], t)
+
+	routes, err = s.ShowRoute(ForPrefix("2001:db8::/32"), All())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("routes", 1, len(routes), t)
+
+	r = routes[0]
+	assert.StringEqual("table", "master6", r.Table, t)
+	assert.StringEqual("gateway", "2001:db8:ffff::1", r.Gateway, t)
+	assert.StringEqual("as path", "65001", strings.Join(r.ASPath, " "), t)
+	assert.StringEqual("bgp next hop", "2001:db8:ffff::1 fe80::1", r.BGPNextHop, t)
+}
+
+// TestShowRouteNotInTable verifies that no routes are returned
+// if the prefix is not in the table
+func TestShowRouteNotInTable(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show route for 172.16.0.0/12": "8001 Network not in table\n",
+	})
+
+	routes, err := s.ShowRoute(ForPrefix("172.16.0.0/12"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("routes", 0, len(routes), t)
+}
+
+// TestRouteCount verifies parsing of `show route count` for populated and empty tables
+func TestRouteCount(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show route table master4 count": "1007-650000 of 700000 routes for 640000 networks in table master4\n" +
+			"0014 Total: 650000 of 700000 routes for 640000 networks in 1 tables\n",
+		"show route table empty count": "1007-0 of 0 routes for 0 networks in table empty\n" +
+			"0014 Total: 0 of 0 routes for 0 networks in 1 tables\n",
+		"show route protocol bgp1 count":     "0014 12 of 12 routes for 12 networks\n",
+		"show route for 172.16.0.0/12 count": "8001 Network not in table\n",
+	})
+
+	tests := []struct {
+		name     string
+		opts     []RouteOption
+		expected int
+	}{
+		{name: "populated", opts: []RouteOption{Table("master4")}, expected: 650000},
+		{name: "empty", opts: []RouteOption{Table("empty")}, expected: 0},
+		{name: "protocol", opts: []RouteOption{ForProtocol("bgp1")}, expected: 12},
+		{name: "not in table", opts: []RouteOption{ForPrefix("172.16.0.0/12")}, expected: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := s.RouteCount(test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.IntEqual("count", test.expected, n, t)
+		})
+	}
+
+	_, err := s.RouteCount(Table("master4"), ForProtocol("bgp1"))
+	_, ok := err.(*BirdError)
+	assert.True("*BirdError returned", ok, t)
+}
+
+// TestShowRouteCommunities verifies parsing of the communities listed by `show route all`
+func TestShowRouteCommunities(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show route all": "1007-Table master4:\n" +
+			" 10.1.0.0/16          unicast [bgp1 2021-02-28] * (100) [AS65001i]\n" +
+			" \tvia 192.168.1.2 on eth0\n" +
+			"1008-\tType: BGP univ\n" +
+			"1012-\tBGP.origin: IGP\n" +
+			" \tBGP.as_path: 65001\n" +
+			" \tBGP.next_hop: 192.168.1.2\n" +
+			" \tBGP.community: (65000,1) (65000,2) (65535,65281)\n" +
+			" \tBGP.ext_community: (rt, 65000, 100) (ro, 10.0.0.1, 5)\n" +
+			" \tBGP.large_community: (65000, 1, 2) (4200000000, 3, 4)\n" +
+			" 10.2.0.0/16          unicast [bgp1 2021-02-28] * (100) [AS65001i]\n" +
+			" \tvia 192.168.1.2 on eth0\n" +
+			"1008-\tType: BGP univ\n" +
+			"1012-\tBGP.origin: IGP\n" +
+			" \tBGP.as_path: 65001\n" +
+			"0000 \n",
+	})
+
+	routes, err := s.ShowRoute(All())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("routes", 2, len(routes), t)
+
+	r := routes[0]
+	assert.IntEqual("communities", 3, len(r.Communities), t)
+	assert.True("community", r.Communities[2] == [2]int{65535, 65281}, t)
+	assert.IntEqual("large communities", 2, len(r.LargeCommunities), t)
+	assert.True("large community", r.LargeCommunities[1] == [3]int{4200000000, 3, 4}, t)
+	assert.IntEqual("ext communities", 2, len(r.ExtCommunities), t)
+	assert.StringEqual("ext community", "ro, 10.0.0.1, 5", r.ExtCommunities[1], t)
+
+	r = routes[1]
+	assert.IntEqual("communities", 0, len(r.Communities), t)
+	assert.IntEqual("large communities", 0, len(r.LargeCommunities), t)
+	assert.IntEqual("ext communities", 0, len(r.ExtCommunities), t)
+}
+
+// TestShowRoutePrefixValidation verifies that only valid IPv4 and IPv6 prefixes are sent to Bird
+func TestShowRoutePrefixValidation(t *testing.T) {
+	route := "1007-Table master:\n" +
+		" 10.0.0.0/8         via 192.168.1.1 on eth0 [bgp1 2018-12-21] * (100) [AS65001i]\n" +
+		"0000 \n"
+	valid := []string{"10.0.0.0/8", "192.168.1.1/32", "192.168.1.1", "2001:db8::/32", "2001:db8::1/128", "2001:db8::1"}
+
+	replies := make(map[string]string)
+	for _, prefix := range valid {
+		replies["show route for "+prefix] = route
+	}
+	s := connectFakeBird(t, replies)
+
+	for _, prefix := range valid {
+		routes, err := s.ShowRoute(ForPrefix(prefix))
+		if err != nil {
+			t.Fatalf("%s: %v", prefix, err)
+		}
+		assert.IntEqual(prefix, 1, len(routes), t)
+	}
+
+	o := &recordingObserver{}
+	s = connectFakeBird(t, replies, WithObserver(o))
+	for _, prefix := range []string{"10.0.0.0/33", "2001:db8::/129", "10.0.0", "2001:db8:::1", "fe80::1%eth0", "10.0.0.0/8 all"} {
+		_, err := s.ShowRoute(ForPrefix(prefix))
+		assert.True(prefix+" rejected", err != nil && strings.Contains(err.Error(), "invalid prefix"), t)
+
+		_, err = s.RouteCount(ForPrefix(prefix))
+		assert.True(prefix+" count rejected", err != nil, t)
+	}
+	assert.IntEqual("queries sent", 0, len(o.queries), t)
+}
+
+// TestShowRouteFilterViews verifies querying routes as seen by the filters of a protocol
+func TestShowRouteFilterViews(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show route export bgp1": "1007-Table master4:\n" +
+			"1007-10.0.0.0/8           unicast [static1 2021-02-28] * (200)\n" +
+			" \tvia 192.168.1.1 on eth0\n" +
+			"0000 \n",
+		"show route noexport bgp1": "1007-Table master4:\n" +
+			"1007-192.168.0.0/16       unicast [static1 2021-02-28] * (200)\n" +
+			" \tvia 192.168.1.1 on eth0\n" +
+			" 172.16.0.0/12        unicast [static1 2021-02-28] * (200)\n" +
+			" \tvia 192.168.1.1 on eth0\n" +
+			"0000 \n",
+		"show route preexport bgp1": "1007-Table master4:\n" +
+			"1007-10.0.0.0/8           unicast [static1 2021-02-28] * (200)\n" +
+			" \tvia 192.168.1.1 on eth0\n" +
+			"0000 \n",
+		"show route import table bgp2": "1007-Table bgp2.ipv4:\n" +
+			"1007-203.0.113.0/24       unicast [bgp2 2021-02-28] * (100) [AS65002i]\n" +
+			" \tvia 198.51.100.1 on eth1\n" +
+			"0000 \n",
+		"show route export bgp3":                 "0000 \n",
+		"show route for 10.1.0.0/16 export bgp1": "8001 Network not in table\n",
+	})
+
+	routes, err := s.ShowRoute(Export("bgp1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.IntEqual("exported", 1, len(routes), t)
+	assert.StringEqual("exported result", FilterAccepted, routes[0].FilterResult, t)
+
+	routes, err = s.ShowRoute(NoExport("bgp1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.IntEqual("not exported", 2, len(routes), t)
+	for _, rt := range routes {
+		assert.StringEqual(rt.Network+" result", FilterRejected, rt.FilterResult, t)
+	}
+
+	routes, err = s.ShowRoute(PreExport("bgp1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.IntEqual("pre export", 1, len(routes), t)
+	assert.StringEqual("pre export result", "", routes[0].FilterResult, t)
+
+	routes, err = s.ShowRoute(PreImport("bgp2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.IntEqual("pre import", 1, len(routes), t)
+	assert.StringEqual("pre import table", "bgp2.ipv4", routes[0].Table, t)
+	assert.StringEqual("pre import network", "203.0.113.0/24", routes[0].Network, t)
+
+	for _, opts := range [][]RouteOption{{Export("bgp3")}, {ForPrefix("10.1.0.0/16"), Export("bgp1")}} {
+		routes, err = s.ShowRoute(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.IntEqual("nothing to show", 0, len(routes), t)
+	}
+
+	_, err = s.ShowRoute(Export("bgp1 all"))
+	assert.True("invalid protocol rejected", err != nil, t)
+}
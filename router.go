@@ -0,0 +1,66 @@
+package birdsocket
+
+import "fmt"
+
+// Down shuts Bird down: all protocols are stopped, the routes exported to the kernel
+// are withdrawn and the daemon exits, so the connection is closed by Bird afterwards.
+// If Bird denies the command, a *BirdError matching ErrAccessDenied is returned.
+// ErrRestricted is returned without sending the command on restricted sessions
+func (s *BirdSocket) Down() error {
+	return s.routerCommand("down", CodeShutdownOrdered)
+}
+
+// GracefulRestart shuts Bird down for a graceful restart: protocols supporting graceful restart
+// are stopped without notifying their neighbors (which keep forwarding to routes learned before)
+// and the daemon exits, so it has to be started again by the caller or a supervisor shortly after.
+// Not supported by Bird 1.6. If Bird denies the command, a *BirdError matching ErrAccessDenied is returned.
+// ErrRestricted is returned without sending the command on restricted sessions
+func (s *BirdSocket) GracefulRestart() error {
+	return s.routerCommand("graceful restart", CodeGracefulRestartOrdered)
+}
+
+// SetServerTimeout sets the time of inactivity after which Bird closes the connection
+// by sending `timeout`, 0 disables the timeout. Idle connections can be kept open
+// using WithKeepAlive as well. ErrRestricted is returned without sending the command on restricted sessions
+func (s *BirdSocket) SetServerTimeout(seconds int) error {
+	if seconds < 0 {
+		return fmt.Errorf("invalid timeout: %d", seconds)
+	}
+
+	return s.routerCommand(fmt.Sprintf("timeout %d", seconds), CodeReplyOK)
+}
+
+// Dump makes Bird write the internal state of what (e.g. routes, protocols, interfaces
+// or resources) to its debug log by sending `dump <what>`. Unknown targets are rejected
+// by Bird with a *BirdError reporting a syntax error. If Bird denies the command,
+// a *BirdError matching ErrAccessDenied is returned.
+// ErrRestricted is returned without sending the command on restricted sessions
+func (s *BirdSocket) Dump(what string) error {
+	if !plainArgRegex.MatchString(what) {
+		return fmt.Errorf("invalid dump target: %q", what)
+	}
+
+	return s.routerCommand("dump "+what, CodeReplyOK)
+}
+
+func (s *BirdSocket) routerCommand(cmd string, code int) error {
+	if s.restricted {
+		return ErrRestricted
+	}
+
+	r, err := s.QueryChecked(cmd)
+	if err != nil {
+		return err
+	}
+
+	t := r.terminal()
+	if t == nil {
+		return fmt.Errorf("incomplete reply to %s", cmd)
+	}
+
+	if t.Code != code {
+		return fmt.Errorf("unexpected reply to %s: %04d %s", cmd, t.Code, t.Message)
+	}
+
+	return nil
+}
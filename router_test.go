@@ -0,0 +1,99 @@
+package birdsocket
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestDown verifies the acknowledgment of `down`
+func TestDown(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"down":             "0007 Shutdown ordered\n",
+		"graceful restart": "0025 Graceful restart ordered\n",
+	})
+
+	if err := s.Down(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.GracefulRestart(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestDownAccessDenied verifies the errors returned if shutting down is not allowed
+func TestDownAccessDenied(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"down":             "8007 Access denied\n",
+		"graceful restart": "8007 Access denied\n",
+	})
+
+	assert.True("down", errors.Is(s.Down(), ErrAccessDenied), t)
+	assert.True("graceful restart", errors.Is(s.GracefulRestart(), ErrAccessDenied), t)
+
+	s = connectFakeBird(t, map[string]string{
+		"restrict": "0016 Access restricted\n",
+	}, WithRestricted())
+
+	assert.True("down on restricted session", errors.Is(s.Down(), ErrAccessDenied), t)
+	assert.True("graceful restart on restricted session", errors.Is(s.GracefulRestart(), ErrAccessDenied), t)
+}
+
+// TestDownUnexpectedReply verifies that replies not acknowledging the shutdown are rejected
+func TestDownUnexpectedReply(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"down": "0000 \n",
+	})
+
+	assert.True("error returned", s.Down() != nil, t)
+}
+
+// TestSetServerTimeout verifies the command sent to set the timeout of the session
+func TestSetServerTimeout(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"timeout 300": "0000 \n",
+		"timeout 0":   "0000 \n",
+	})
+
+	for _, seconds := range []int{300, 0} {
+		if err := s.SetServerTimeout(seconds); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, ok := s.SetServerTimeout(10).(*BirdError)
+	assert.True("*BirdError returned", ok, t)
+	assert.True("negative timeout rejected", s.SetServerTimeout(-1) != nil, t)
+
+	restricted := connectFakeBird(t, map[string]string{
+		"restrict": "0016 Access restricted\n",
+	}, WithRestricted())
+	assert.True("ErrRestricted", restricted.SetServerTimeout(0) == ErrRestricted, t)
+	assert.True("ErrAccessDenied", errors.Is(restricted.Dump("routes"), ErrAccessDenied), t)
+}
+
+// TestDump verifies the acknowledgment of `dump` and the errors for invalid targets
+func TestDump(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"dump routes":    "0000 \n",
+		"dump protocols": "0000 \n",
+		"dump sockets":   "8007 Access denied\n",
+	})
+
+	for _, what := range []string{"routes", "protocols"} {
+		if err := s.Dump(what); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	birdErr, ok := s.Dump("everything").(*BirdError)
+	assert.True("*BirdError returned", ok, t)
+	if ok {
+		assert.IntEqual("code", CodeSyntaxError, birdErr.Code, t)
+	}
+
+	assert.True("invalid target rejected", s.Dump("routes\ndown") != nil, t)
+	assert.True("ErrAccessDenied", errors.Is(s.Dump("sockets"), ErrAccessDenied), t)
+}
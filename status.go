@@ -0,0 +1,105 @@
+package birdsocket
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the layouts used by Bird to print timestamps.
+// BIRD 2.x appends milliseconds, which are accepted by time.Parse anyway
+var timeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"02-01-2006 15:04:05",
+	"2006-01-02",
+	"02-01-2006",
+}
+
+// Status is the parsed reply of `show status`
+type Status struct {
+	Version             string
+	RouterID            string
+	Hostname            string
+	ServerTime          time.Time
+	LastReboot          time.Time
+	LastReconfiguration time.Time
+	Message             string
+}
+
+// ShowStatus queries the status of the Bird daemon.
+// Timestamps in a format not known are left zero
+func (s *BirdSocket) ShowStatus() (*Status, error) {
+	r, err := s.QueryChecked("show status")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStatus(r), nil
+}
+
+// RouterID returns the router ID of the connected Bird daemon.
+// It is queried by `show status` once and cached until the socket is connected again
+func (s *BirdSocket) RouterID() (string, error) {
+	s.mu.Lock()
+	id := s.routerID
+	s.mu.Unlock()
+
+	if id != "" {
+		return id, nil
+	}
+
+	st, err := s.ShowStatus()
+	if err != nil {
+		return "", err
+	}
+
+	if st.RouterID == "" {
+		return "", errors.New("router ID not found in status")
+	}
+
+	s.mu.Lock()
+	s.routerID = st.RouterID
+	s.mu.Unlock()
+
+	return st.RouterID, nil
+}
+
+func parseStatus(r *Reply) *Status {
+	st := &Status{}
+
+	for _, l := range r.Lines {
+		if !l.Continued {
+			st.Message = l.Message
+			continue
+		}
+
+		msg := strings.TrimSpace(l.Message)
+		switch {
+		case l.Code == CodeVersion:
+			st.Version = strings.TrimPrefix(msg, "BIRD ")
+		case strings.HasPrefix(msg, "Router ID is "):
+			st.RouterID = strings.TrimPrefix(msg, "Router ID is ")
+		case strings.HasPrefix(msg, "Hostname is "):
+			st.Hostname = strings.TrimPrefix(msg, "Hostname is ")
+		case strings.HasPrefix(msg, "Current server time is "):
+			st.ServerTime = parseTime(strings.TrimPrefix(msg, "Current server time is "))
+		case strings.HasPrefix(msg, "Last reboot on "):
+			st.LastReboot = parseTime(strings.TrimPrefix(msg, "Last reboot on "))
+		case strings.HasPrefix(msg, "Last reconfiguration on "):
+			st.LastReconfiguration = parseTime(strings.TrimPrefix(msg, "Last reconfiguration on "))
+		}
+	}
+
+	return st
+}
+
+// parseTime parses a timestamp printed by Bird in local time
+func parseTime(s string) time.Time {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(s), time.Local); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,86 @@
+package birdsocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestShowStatusV1 verifies parsing of `show status` as sent by BIRD 1.6
+func TestShowStatusV1(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show status": "1000-BIRD 1.6.4\n" +
+			"1011-Router ID is 192.168.1.9\n" +
+			" Current server time is 2018-12-27 12:15:01\n" +
+			" Last reboot on 2018-12-21 12:35:11\n" +
+			" Last reconfiguration on 2018-12-22 08:00:00\n" +
+			"0013 Daemon is up and running\n",
+	})
+
+	st, err := s.ShowStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("version", "1.6.4", st.Version, t)
+	assert.StringEqual("router id", "192.168.1.9", st.RouterID, t)
+	assert.StringEqual("hostname", "", st.Hostname, t)
+	assert.True("server time", st.ServerTime.Equal(time.Date(2018, 12, 27, 12, 15, 1, 0, time.Local)), t)
+	assert.True("last reboot", st.LastReboot.Equal(time.Date(2018, 12, 21, 12, 35, 11, 0, time.Local)), t)
+	assert.True("last reconfiguration", st.LastReconfiguration.Equal(time.Date(2018, 12, 22, 8, 0, 0, 0, time.Local)), t)
+	assert.StringEqual("message", "Daemon is up and running", st.Message, t)
+}
+
+// TestShowStatusV2 verifies parsing of `show status` as sent by BIRD 2.0
+func TestShowStatusV2(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show status": "1000-BIRD 2.0.8\n" +
+			"1011-Router ID is 10.0.0.1\n" +
+			" Hostname is router1\n" +
+			" Current server time is 2021-03-01 10:20:30.123\n" +
+			" Last reboot on 2021-02-28 09:00:00.456\n" +
+			" Last reconfiguration on 2021-02-28 09:00:00.456\n" +
+			"0013 Daemon is up and running\n",
+	})
+
+	st, err := s.ShowStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("version", "2.0.8", st.Version, t)
+	assert.StringEqual("router id", "10.0.0.1", st.RouterID, t)
+	assert.StringEqual("hostname", "router1", st.Hostname, t)
+	assert.True("server time", st.ServerTime.Equal(time.Date(2021, 3, 1, 10, 20, 30, 123000000, time.Local)), t)
+	assert.True("last reboot", st.LastReboot.Equal(time.Date(2021, 2, 28, 9, 0, 0, 456000000, time.Local)), t)
+	assert.StringEqual("message", "Daemon is up and running", st.Message, t)
+}
+
+// TestRouterID verifies that the router ID is queried once and cached until reconnecting
+func TestRouterID(t *testing.T) {
+	o := &recordingObserver{}
+	s := connectFakeBird(t, map[string]string{
+		"show status": "1000-BIRD 2.0.7\n" +
+			"1011-Router ID is 192.168.1.9\n" +
+			"0013 Daemon is up and running\n",
+	}, WithObserver(o))
+
+	for i := 0; i < 2; i++ {
+		id, err := s.RouterID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.StringEqual("router id", "192.168.1.9", id, t)
+	}
+	assert.IntEqual("queries", 1, len(o.queries), t)
+
+	if _, err := s.Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.RouterID(); err != nil {
+		t.Fatal(err)
+	}
+	assert.IntEqual("queries after reconnect", 2, len(o.queries), t)
+}
@@ -0,0 +1,336 @@
+package birdsocket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// drainTimeout bounds discarding the rest of an aborted reply
+const drainTimeout = time.Second
+
+// QueryStream sends an query to Bird and calls fn for every line of the reply
+// as soon as it was received, without holding the whole reply in memory.
+// Lines are passed without the trailing newline and are only valid until fn returns.
+// Reading stops after the last line of the reply or when fn returns an error,
+// which is then returned by QueryStream after discarding the rest of the reply.
+// If reading from the socket fails, an incomplete line received before
+// is passed to fn prior to returning the error
+func (s *BirdSocket) QueryStream(qry string, fn func(line []byte) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.send(qry); err != nil {
+		return err
+	}
+
+	return s.readLines(s.conn, func(line []byte) error {
+		return fn(bytes.TrimSuffix(line, []byte("\n")))
+	})
+}
+
+// QueryTo sends an query to Bird and writes the raw reply to w as soon as it was received,
+// line by line up to the last line of the reply. The number of bytes written is returned.
+// If writing to w fails, reading stops and the error is returned
+func (s *BirdSocket) QueryTo(w io.Writer, qry string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.send(qry); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err := s.readLines(s.conn, func(line []byte) error {
+		written, err := w.Write(line)
+		n += int64(written)
+		return err
+	})
+
+	return n, err
+}
+
+// QueryReader sends an query to Bird and returns a reader yielding the raw reply
+// as soon as it was received, e.g. to be consumed by a bufio.Scanner.
+// The reader returns io.EOF after the last line of the reply. Other queries wait
+// until the reply was read completely: closing the reader early consumes (and discards)
+// the rest of the reply, so the connection is ready for the next query once Close returns
+func (s *BirdSocket) QueryReader(qry string) (io.ReadCloser, error) {
+	s.mu.Lock()
+
+	if err := s.send(qry); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	r := &replyReader{PipeReader: pr, done: make(chan struct{})}
+	go func() {
+		defer close(r.done)
+		defer s.mu.Unlock()
+
+		err := s.readLines(s.conn, func(line []byte) error {
+			// a failed write means the reader was closed and the rest of the reply is discarded
+			pw.Write(line)
+			return nil
+		})
+		pw.CloseWithError(err)
+	}()
+
+	return r, nil
+}
+
+// replyReader is the reader returned by QueryReader
+type replyReader struct {
+	*io.PipeReader
+	done chan struct{}
+}
+
+// Close closes the reader and waits for the rest of the reply to be consumed
+func (r *replyReader) Close() error {
+	err := r.PipeReader.Close()
+	<-r.done
+
+	return err
+}
+
+// readLines reads the reply from conn and calls fn for every complete line
+// (including its trailing newline) until the last line of the reply was received.
+// Only newly completed lines are inspected, so reading is linear in the size of the reply.
+// Bytes received after the last line (e.g. replies to pipelined commands) are kept
+// for the next call. When reading fails, the incomplete remainder is passed to fn
+// before returning the error.
+// If the reply exceeds the maximum reply size, conn is closed and ErrReplyTooLarge is returned
+func (s *BirdSocket) readLines(conn net.Conn, fn func(line []byte) error) error {
+	s.lastCode, s.lastReads, s.lastBytes = -1, 0, 0
+	if err := s.setReadDeadline(conn); err != nil {
+		return err
+	}
+
+	buf := make([]byte, s.bufferSize)
+	pending := append(make([]byte, 0, s.bufferSize), s.pending...)
+	s.pending = nil
+	total := len(pending)
+	s.lastBytes = total
+	// pending[:scanned] is known not to contain a newline, so long lines
+	// split across many reads are not scanned over and over again
+	scanned := 0
+	var err error
+	for {
+		// lines (and reply codes) may be split across reads, so only complete lines
+		// are handed over while the remainder is kept for the next read
+		consumed := 0
+		for {
+			i := bytes.IndexByte(pending[consumed+scanned:], '\n')
+			if i < 0 {
+				break
+			}
+			i += scanned
+			scanned = 0
+
+			line := pending[consumed : consumed+i+1]
+			consumed += i + 1
+
+			if s.normalizeNewlines && i > 0 && line[i-1] == '\r' {
+				line[i-1] = '\n'
+				line = line[:i]
+				i--
+			}
+
+			if s.completion != nil {
+				done, terminal := s.completion(line[:i])
+				if !done || terminal {
+					if err := fn(line); err != nil {
+						if !done {
+							s.drain(conn, pending[consumed:])
+						} else {
+							s.keepPending(pending[consumed:])
+						}
+						return err
+					}
+				}
+
+				if done {
+					if code, _, ok := parseCode(line[:i]); ok {
+						s.lastCode = code
+					}
+					if s.logger != nil {
+						s.logger.Debugf("reply completed")
+					}
+					s.keepPending(pending[consumed:])
+					return nil
+				}
+				continue
+			}
+
+			if err := fn(line); err != nil {
+				if !isTerminalLine(line[:i]) {
+					s.drain(conn, pending[consumed:])
+				} else {
+					s.keepPending(pending[consumed:])
+				}
+				return err
+			}
+
+			if isTerminalLine(line[:i]) {
+				s.lastCode, _, _ = parseCode(line[:i])
+				if s.logger != nil {
+					s.logger.Debugf("reply completed with code %s", line[:4])
+				}
+				s.keepPending(pending[consumed:])
+				return nil
+			}
+		}
+		if consumed > 0 {
+			pending = append(pending[:0], pending[consumed:]...)
+		}
+		scanned = len(pending)
+
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				// the rest of the reply may still arrive and has to be discarded before the next query
+				s.dirty = true
+				if s.logger != nil {
+					s.logger.Debugf("read deadline exceeded")
+				}
+			}
+
+			if len(pending) > 0 {
+				if err := fn(pending); err != nil {
+					return err
+				}
+			}
+			return err
+		}
+
+		var n int
+		n, err = conn.Read(buf[:])
+		pending = append(pending, buf[:n]...)
+		s.dump("<<<", buf[:n])
+
+		total += n
+		s.lastReads++
+		s.lastBytes = total
+		if s.maxReplySize > 0 && total > s.maxReplySize {
+			conn.Close()
+			if conn == s.conn {
+				s.conn = nil
+			}
+			return ErrReplyTooLarge
+		}
+		if s.logger != nil {
+			s.logger.Debugf("read %d bytes", n)
+		}
+	}
+}
+
+// keepPending keeps the bytes received after the last line of a reply for the next read
+func (s *BirdSocket) keepPending(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+
+	s.pending = append([]byte{}, b...)
+}
+
+// drain discards the rest of a reply whose reading was aborted up to its last line,
+// so the connection can be used for the next query. pending holds the bytes
+// already read but not inspected yet. Draining is bounded by drainTimeout,
+// if the last line was not received by then the connection is marked dirty
+func (s *BirdSocket) drain(conn net.Conn, pending []byte) {
+	pending = append([]byte{}, pending...)
+	if err := conn.SetReadDeadline(time.Now().Add(drainTimeout)); err != nil {
+		s.dirty = true
+		return
+	}
+
+	buf := make([]byte, s.bufferSize)
+	for {
+		for {
+			i := bytes.IndexByte(pending, '\n')
+			if i < 0 {
+				break
+			}
+
+			line := pending[:i]
+			pending = pending[i+1:]
+			if s.normalizeNewlines {
+				line = bytes.TrimSuffix(line, []byte("\r"))
+			}
+
+			if s.isLastLine(line) {
+				if s.logger != nil {
+					s.logger.Debugf("drained rest of aborted reply")
+				}
+				s.keepPending(pending)
+				return
+			}
+		}
+
+		n, err := conn.Read(buf)
+		pending = append(pending, buf[:n]...)
+		s.dump("<<<", buf[:n])
+		if err != nil {
+			s.dirty = true
+			return
+		}
+	}
+}
+
+// recoverConn makes a dirty connection usable again by draining the rest of the aborted reply
+// or, if it does not arrive in time, reconnecting
+func (s *BirdSocket) recoverConn() error {
+	s.dirty = false
+	s.drain(s.conn, nil)
+	if !s.dirty {
+		return nil
+	}
+
+	if s.logger != nil {
+		s.logger.Debugf("reconnecting since aborted reply was not drained")
+	}
+
+	s.dirty = false
+	s.closeConn()
+	_, err := s.connect(context.Background(), true)
+	return err
+}
+
+// isLastLine reports whether line (without the newline) completes the reply
+func (s *BirdSocket) isLastLine(line []byte) bool {
+	if s.completion != nil {
+		done, _ := s.completion(line)
+		return done
+	}
+
+	return isTerminalLine(line)
+}
+
+// setReadDeadline sets the read deadline of conn for reading a reply,
+// bounded by the total deadline counted from sending the query.
+// If neither applies, a deadline left over from a previous read is cleared
+func (s *BirdSocket) setReadDeadline(conn net.Conn) error {
+	var deadline time.Time
+	if s.readDeadline != nil {
+		deadline = time.Now().Add(*s.readDeadline)
+	}
+
+	if s.totalDeadline > 0 {
+		total := s.lastUsed.Add(s.totalDeadline)
+		if deadline.IsZero() || total.Before(deadline) {
+			deadline = total
+		}
+	}
+
+	return conn.SetReadDeadline(deadline)
+}
+
+// isTerminalLine reports whether line is the last line of a reply
+func isTerminalLine(line []byte) bool {
+	_, continued, ok := parseCode(line)
+	return ok && !continued
+}
@@ -0,0 +1,528 @@
+package birdsocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// serveChunks returns a handler answering the first query
+// by writing the given chunks with a short pause in between
+func serveChunks(chunks ...string) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+		bufio.NewReader(conn).ReadString('\n')
+
+		for _, c := range chunks {
+			conn.Write([]byte(c))
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+// TestQueryStream verifies that lines split across
+// reads are reassembled before being passed on
+func TestQueryStream(t *testing.T) {
+	f := newFakeBird(t, serveChunks(
+		"1007-10.0.0.0/8 via 192.168.1.1 on eth0 [sta",
+		"tic1 2018-12-21] * (200)\n 10.1.0.0/16 via 192.168.1.2 on eth0 [static1 2018-12-21] * (200)\n",
+		"0000 \n",
+	))
+
+	s := NewSocket(f.path, WithReadDeadline(5*time.Second))
+	_, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	lines := make([]string, 0)
+	err = s.QueryStream("show route", func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("lines", 3, len(lines), t)
+	assert.StringEqual("line 0", "1007-10.0.0.0/8 via 192.168.1.1 on eth0 [static1 2018-12-21] * (200)", lines[0], t)
+	assert.StringEqual("line 1", " 10.1.0.0/16 via 192.168.1.2 on eth0 [static1 2018-12-21] * (200)", lines[1], t)
+	assert.StringEqual("line 2", "0000 ", lines[2], t)
+}
+
+// TestQueryStreamCallbackError verifies that reading stops
+// as soon as the callback returns an error
+func TestQueryStreamCallbackError(t *testing.T) {
+	f := newFakeBird(t, serveChunks(
+		"1007-10.0.0.0/8 via 192.168.1.1 on eth0 [static1 2018-12-21] * (200)\n",
+		" 10.1.0.0/16 via 192.168.1.2 on eth0 [static1 2018-12-21] * (200)\n",
+		"0000 \n",
+	))
+
+	s := NewSocket(f.path, WithReadDeadline(5*time.Second))
+	_, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	stop := errors.New("stop")
+	calls := 0
+	err = s.QueryStream("show route", func(line []byte) error {
+		calls++
+		return stop
+	})
+
+	assert.True("callback error returned", err == stop, t)
+	assert.IntEqual("calls", 1, calls, t)
+}
+
+// byteConn is a connection returning the reply one byte per read
+type byteConn struct {
+	net.Conn
+	r     io.Reader
+	reads int
+}
+
+func (c *byteConn) Read(b []byte) (int, error) {
+	c.reads++
+	return c.r.Read(b[:1])
+}
+
+func (c *byteConn) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (c *byteConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *byteConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *byteConn) Close() error {
+	return nil
+}
+
+// TestQueryByteByByte simulate a scenario in which every
+// reply code is split across multiple reads
+func TestQueryByteByByte(t *testing.T) {
+	out := "1000-BIRD 1.6.4\n" +
+		"1011-Router ID is 192.168.1.9\n" +
+		" Current server time is 2018-12-27 12:15:01\n" +
+		"0013 Daemon is up and running\n"
+	conn := &byteConn{r: strings.NewReader(fakeWelcome + out + "1000-BIRD 1.6.4\n")}
+
+	s := NewSocket("", WithDialer(func(ctx context.Context) (net.Conn, error) {
+		return conn, nil
+	}))
+	_, err := s.Connect(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := s.Query("show status", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("reply", out, string(b), t)
+	assert.IntEqual("reads", len(fakeWelcome)+len(out), conn.reads, t)
+}
+
+// TestCompletionFunc verifies that a custom completion func decides the end of a reply
+func TestCompletionFunc(t *testing.T) {
+	completion := func(line []byte) (bool, bool) {
+		return string(line) == "END", false
+	}
+	s := NewSocket("", WithCompletionFunc(completion), WithReadDeadline(5*time.Second), WithDialer(pipeDialer(serveRepliesWithWelcome(fakeWelcome+"END\n", map[string]string{
+		"show status": "BIRD 1.6.4\n0013 Daemon is up and running\nEND\n",
+		"show foo":    "9001 syntax error\nEND\n",
+	}))))
+	welcome, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	assert.StringEqual("welcome", fakeWelcome, string(welcome), t)
+
+	out, err := s.Query("show status", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("reply", "BIRD 1.6.4\n0013 Daemon is up and running\n", string(out), t)
+
+	out, err = s.Query("show foo", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("reply", "9001 syntax error\n", string(out), t)
+}
+
+// TestTotalDeadline simulate a scenario in which Bird sends a reply line by line
+// never completing it and verifies that the query is aborted after the total deadline
+func TestTotalDeadline(t *testing.T) {
+	s := NewSocket("", WithTotalDeadline(200*time.Millisecond), WithReadDeadline(5*time.Second), WithDialer(pipeDialer(func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+		bufio.NewReader(conn).ReadString('\n')
+
+		for i := 0; i < 100; i++ {
+			if _, err := conn.Write([]byte(" direct1  Direct   master   up     2018-12-21\n")); err != nil {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	})))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	start := time.Now()
+	out, err := s.Query("show protocols", true)
+	assert.True("ErrDeadlineExceeded", errors.Is(err, ErrDeadlineExceeded), t)
+	assert.True("bounded by total deadline", time.Since(start) < time.Second, t)
+	assert.True("partial reply", len(out) > 0, t)
+}
+
+// TestQueryTo verifies that a reply received in multiple chunks is written to the writer
+func TestQueryTo(t *testing.T) {
+	chunks := []string{
+		"1007-10.0.0.0/8 via 192.168.1.1 on eth0 [sta",
+		"tic1 2018-12-21] * (200)\n 10.1.0.0/16 via 192.168.1.2 on eth0 [static1 2018-12-21] * (200)\n",
+		"0000 \n",
+	}
+	f := newFakeBird(t, serveChunks(chunks...))
+
+	s := NewSocket(f.path, WithReadDeadline(5*time.Second))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var buf bytes.Buffer
+	n, err := s.QueryTo(&buf, "show route")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join(chunks, "")
+	assert.StringEqual("reply", expected, buf.String(), t)
+	assert.IntEqual("bytes written", len(expected), int(n), t)
+}
+
+// TestNormalizeNewlines verifies that replies framed by CRLF are parsed like replies framed by LF
+func TestNormalizeNewlines(t *testing.T) {
+	reply := "1000-BIRD 1.6.4\r\n" +
+		"1011-Router ID is 192.168.1.9\r\n" +
+		" Description: foo\rbar\r\n" +
+		"0013 Daemon is up and running\r\n"
+
+	s := NewSocket("", WithReadDeadline(5*time.Second), WithDialer(pipeDialer(serveRepliesWithWelcome("0001 BIRD 1.6.4 ready.\r\n", map[string]string{
+		"show status": reply,
+	}))))
+	welcome, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	assert.StringEqual("welcome", "0001 BIRD 1.6.4 ready.\n", string(welcome), t)
+
+	out, err := s.Query("show status", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1000-BIRD 1.6.4\n" +
+		"1011-Router ID is 192.168.1.9\n" +
+		" Description: foo\rbar\n" +
+		"0013 Daemon is up and running\n"
+	assert.StringEqual("reply", expected, string(out), t)
+}
+
+// TestNormalizeNewlinesDisabled verifies that CRLF is kept if normalizing newlines is disabled
+func TestNormalizeNewlinesDisabled(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show status": "0013 Daemon is up and running\r\n",
+	}, WithNormalizeNewlines(false), WithReadDeadline(5*time.Second))
+
+	out, err := s.Query("show status", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("reply", "0013 Daemon is up and running\r\n", string(out), t)
+}
+
+// replayConn is a connection returning a prepared reply without any I/O
+type replayConn struct {
+	net.Conn
+	r *bytes.Reader
+}
+
+func (c *replayConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func (c *replayConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+// syntheticRouteDump returns a `show route` reply of at least size bytes
+func syntheticRouteDump(size int) []byte {
+	var b bytes.Buffer
+	b.WriteString("1007-Table master:\n")
+	for i := 0; b.Len() < size; i++ {
+		fmt.Fprintf(&b, " 10.%d.%d.0/24 via 192.168.1.1 on eth0 [bgp1 2018-12-21 from 192.168.1.1] * (100) [AS65001i]\n", i/256%256, i%256)
+	}
+	b.WriteString("0000 \n")
+
+	return b.Bytes()
+}
+
+// BenchmarkReadFromSocket reads multi-megabyte replies. The throughput stays
+// the same regardless of the size of the reply, since only newly received lines are inspected,
+// while rescanning the whole reply after every read (rescan) slows down with its size
+// and is therefore only run for the smaller replies
+func BenchmarkReadFromSocket(b *testing.B) {
+	for _, size := range []int{256 << 10, 1 << 20, 4 << 20} {
+		reply := syntheticRouteDump(size)
+		longLine := append(append([]byte("1007-"), bytes.Repeat([]byte("x"), size)...), "\n0000 \n"...)
+
+		b.Run(fmt.Sprintf("routes/%dKB", size>>10), func(b *testing.B) {
+			benchmarkReadFromSocket(b, reply)
+		})
+		b.Run(fmt.Sprintf("long-line/%dKB", size>>10), func(b *testing.B) {
+			benchmarkReadFromSocket(b, longLine)
+		})
+		if size > 1<<20 {
+			continue
+		}
+
+		b.Run(fmt.Sprintf("rescan/%dKB", size>>10), func(b *testing.B) {
+			b.SetBytes(int64(len(reply)))
+			for i := 0; i < b.N; i++ {
+				out := make([]byte, 0)
+				for r := bytes.NewReader(reply); r.Len() > 0; {
+					chunk := make([]byte, defaultBufferSize)
+					n, _ := r.Read(chunk)
+					out = append(out, chunk[:n]...)
+					if containsTerminalCode(out) {
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func benchmarkReadFromSocket(b *testing.B, reply []byte) {
+	s := NewSocket("")
+	b.SetBytes(int64(len(reply)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		out, err := s.readFromSocket(&replayConn{r: bytes.NewReader(reply)})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(out) != len(reply) {
+			b.Fatalf("read %d of %d bytes", len(out), len(reply))
+		}
+	}
+}
+
+// TestQueryReader verifies scanning the reply line by line from the reader
+func TestQueryReader(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show protocols": "2002-name     proto    table    state  since       info\n" +
+			"1002-device1  Device   master   up     2018-12-21\n" +
+			" bgp1     BGP      master   up     2018-12-21  Established\n" +
+			"0000 \n",
+		"show status": "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+	})
+
+	r, err := s.QueryReader("show protocols")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+
+	assert.IntEqual("lines", 4, len(lines), t)
+	assert.StringEqual("protocol", " bgp1     BGP      master   up     2018-12-21  Established", lines[2], t)
+
+	out, err := s.Query("show status", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("next query", "1000-BIRD 1.6.4\n0013 Daemon is up and running\n", string(out), t)
+}
+
+// TestQueryReaderClosedEarly verifies that closing the reader before the reply
+// was read completely leaves the connection usable for the next query
+func TestQueryReaderClosedEarly(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show protocols": "2002-name     proto    table    state  since       info\n" +
+			"1002-device1  Device   master   up     2018-12-21\n" +
+			" bgp1     BGP      master   up     2018-12-21  Established\n" +
+			"0000 \n",
+		"show status": "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+	})
+
+	r, err := s.QueryReader("show protocols")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("first line", "2002-name     proto    table    state  since       info\n", line, t)
+	r.Close()
+
+	out, err := s.Query("show status", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("next query", "1000-BIRD 1.6.4\n0013 Daemon is up and running\n", string(out), t)
+}
+
+// TestQueryRouteDataWithDigits verifies that route data starting with four digits
+// does not complete the reply early, even if the data arrives split across reads
+func TestQueryRouteDataWithDigits(t *testing.T) {
+	chunks := []string{
+		"1007-Table master6:\n",
+		"1007-1000::/3              unreachable [static1 2021-02-28] * (200)\n 1000",
+		"::/4              unreachable [static1 2021-02-28] * (200)\n",
+		" 2001 ",
+		"::/16 via fe80::1 on eth0 [bgp1 2021-02-28] * (100) [AS65001i]\n",
+		"1008-\tType: BGP univ\n",
+		"1012-\tBGP.as_path: 6500 2001 1000\n",
+		" 0000 is not a code either\n",
+		"0000 \n",
+	}
+	s := NewSocket("", WithDialer(pipeDialer(serveChunks(chunks...))))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	out, err := s.Query("show route all", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("reply", strings.Join(chunks, ""), string(out), t)
+}
+
+// TestQueryStreamAborted verifies that the rest of a reply is discarded
+// when the callback aborts reading, so the next query gets its own reply
+func TestQueryStreamAborted(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show protocols": "2002-name     proto    table    state  since       info\n" +
+			"1002-device1  Device   master   up     2018-12-21\n" +
+			" bgp1     BGP      master   up     2018-12-21  Established\n" +
+			"0000 \n",
+		"show status": "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+	})
+
+	errAbort := errors.New("abort")
+	err := s.QueryStream("show protocols", func(line []byte) error {
+		return errAbort
+	})
+	assert.True("callback error returned", err == errAbort, t)
+
+	out, err := s.Query("show status", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("next query", "1000-BIRD 1.6.4\n0013 Daemon is up and running\n", string(out), t)
+}
+
+// TestQueryAfterDeadline verifies that the rest of a reply arriving
+// after the read deadline was exceeded is discarded before the next query
+func TestQueryAfterDeadline(t *testing.T) {
+	s := NewSocket("", WithDialer(pipeDialer(func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+
+		r := bufio.NewReader(conn)
+		r.ReadString('\n')
+		conn.Write([]byte("1007-10.0.0.0/8 via 192.168.1.1 on eth0 [static1 2018-12-21] * (200)\n"))
+		time.Sleep(200 * time.Millisecond)
+		conn.Write([]byte(" 10.1.0.0/16 via 192.168.1.2 on eth0 [static1 2018-12-21] * (200)\n0000 \n"))
+
+		r.ReadString('\n')
+		conn.Write([]byte("1000-BIRD 1.6.4\n0013 Daemon is up and running\n"))
+	})))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	_, err := s.QueryWithDeadline("show route", 50*time.Millisecond)
+	assert.True("ErrDeadlineExceeded", errors.Is(err, ErrDeadlineExceeded), t)
+
+	out, err := s.Query("show status", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("next query", "1000-BIRD 1.6.4\n0013 Daemon is up and running\n", string(out), t)
+}
+
+// TestQueryAfterDeadlineReconnects verifies that the connection is re-established
+// if the rest of a reply does not arrive after the read deadline was exceeded
+func TestQueryAfterDeadlineReconnects(t *testing.T) {
+	var connects int32
+	serve := serveReplies(map[string]string{
+		"show status": "1000-BIRD 1.6.4\n0013 Daemon is up and running\n",
+	})
+	s := NewSocket("", WithDialer(pipeDialer(func(conn net.Conn) {
+		if atomic.AddInt32(&connects, 1) > 1 {
+			serve(conn)
+			return
+		}
+
+		conn.Write([]byte(fakeWelcome))
+		r := bufio.NewReader(conn)
+		r.ReadString('\n')
+		conn.Write([]byte("1007-10.0.0.0/8 via 192.168.1.1 on eth0 [static1 2018-12-21] * (200)\n"))
+		io.Copy(ioutil.Discard, r)
+	})))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	_, err := s.QueryWithDeadline("show route", 50*time.Millisecond)
+	assert.True("ErrDeadlineExceeded", errors.Is(err, ErrDeadlineExceeded), t)
+
+	out, err := s.Query("show status", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("next query", "1000-BIRD 1.6.4\n0013 Daemon is up and running\n", string(out), t)
+	assert.IntEqual("connects", 2, int(atomic.LoadInt32(&connects)), t)
+}
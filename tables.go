@@ -0,0 +1,78 @@
+package birdsocket
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Symbol is a symbol defined in the configuration as listed by `show symbols`.
+// Type is the class of the symbol as printed by Bird, e.g. protocol, filter,
+// function, constant or routing table
+type Symbol struct {
+	Name string
+	Type string
+}
+
+// ShowSymbols queries all symbols defined in the configuration
+func (s *BirdSocket) ShowSymbols() ([]Symbol, error) {
+	r, err := s.QueryChecked("show symbols")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSymbols(r), nil
+}
+
+// ShowSymbol queries the symbol name. Symbols not defined are returned with type undefined
+func (s *BirdSocket) ShowSymbol(name string) (*Symbol, error) {
+	cmd, err := NewCommand("show", "symbols").Arg(name).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s.QueryChecked(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := parseSymbols(r)
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("symbol %s not found", name)
+	}
+
+	return &symbols[0], nil
+}
+
+// ShowTables queries the names of the routing tables defined in the configuration.
+// Bird has no command listing the tables only, so they are queried by `show symbols table`
+func (s *BirdSocket) ShowTables() ([]string, error) {
+	r, err := s.QueryChecked("show symbols table")
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]string, 0)
+	for _, sym := range parseSymbols(r) {
+		tables = append(tables, sym.Name)
+	}
+
+	return tables, nil
+}
+
+func parseSymbols(r *Reply) []Symbol {
+	symbols := make([]Symbol, 0)
+	for _, l := range r.Lines {
+		if l.Code != CodeSymbolList {
+			continue
+		}
+
+		// e.g. `master4   	routing table`
+		f := strings.Fields(l.Message)
+		if len(f) == 0 {
+			continue
+		}
+		symbols = append(symbols, Symbol{Name: f[0], Type: strings.Join(f[1:], " ")})
+	}
+
+	return symbols
+}
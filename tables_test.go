@@ -0,0 +1,93 @@
+package birdsocket
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestShowTables verifies parsing of the table listing
+func TestShowTables(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show symbols table": "1010-master4  \trouting table\n" +
+			" master6  \trouting table\n" +
+			" vrf_blue \trouting table\n" +
+			"0000 \n",
+	})
+
+	tables, err := s.ShowTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.StringEqual("tables", "master4 master6 vrf_blue", strings.Join(tables, " "), t)
+}
+
+// TestShowRouteInTable verifies that route queries are scoped to the named table
+func TestShowRouteInTable(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show route table vrf_blue": "1007-Table vrf_blue:\n" +
+			" 10.0.0.0/8           unicast [static1 2021-02-28] * (200)\n" +
+			" \tvia 192.168.1.1 on eth0\n" +
+			"0000 \n",
+		"show route table vrf_blue count": "0014 1 of 1 routes for 1 networks in table vrf_blue\n",
+	})
+
+	routes, err := s.ShowRoute(Table("vrf_blue"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("routes", 1, len(routes), t)
+	assert.StringEqual("table", "vrf_blue", routes[0].Table, t)
+
+	n, err := s.RouteCount(Table("vrf_blue"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("count", 1, n, t)
+}
+
+// TestShowSymbols verifies parsing of the symbols of various types
+func TestShowSymbols(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show symbols": "1010-master4  \trouting table\n" +
+			" device1  \tprotocol\n" +
+			" bgp_in   \tfilter\n" +
+			" is_bogon \tfunction\n" +
+			" LOCAL_AS \tconstant\n" +
+			" roa_v4   \tROA table\n" +
+			"0000 \n",
+		"show symbols bgp_in": "1010-bgp_in   \tfilter\n0000 \n",
+		"show symbols foo":    "1010-foo      \tundefined\n0000 \n",
+	})
+
+	symbols, err := s.ShowSymbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("symbols", 6, len(symbols), t)
+	assert.StringEqual("table", "routing table", symbols[0].Type, t)
+	assert.StringEqual("protocol name", "device1", symbols[1].Name, t)
+	assert.StringEqual("protocol", "protocol", symbols[1].Type, t)
+	assert.StringEqual("function", "function", symbols[3].Type, t)
+	assert.StringEqual("roa", "ROA table", symbols[5].Type, t)
+
+	sym, err := s.ShowSymbol("bgp_in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("filter", "filter", sym.Type, t)
+
+	sym, err = s.ShowSymbol("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("undefined", "undefined", sym.Type, t)
+
+	_, err = s.ShowSymbol("foo\nshow status")
+	assert.True("invalid name rejected", err != nil, t)
+}
@@ -0,0 +1,174 @@
+// Package testserver provides a fake Bird control socket for testing code using birdsocket
+package testserver
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultWelcome is the welcome message sent by default
+const DefaultWelcome = "0001 BIRD 2.0.8 ready.\n"
+
+// Server is a fake Bird control socket listening on a temporary unix socket.
+// Every registered command is answered with its canned reply, all other
+// commands with a syntax error
+type Server struct {
+	// Path is the path of the unix socket to connect to
+	Path string
+
+	listener  net.Listener
+	dir       string
+	welcome   []byte
+	mu        sync.Mutex
+	responses map[string][]byte
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+}
+
+// Option applies options to Server
+type Option func(*Server)
+
+// WithWelcome sets the welcome message sent to every new connection
+func WithWelcome(welcome []byte) Option {
+	return func(s *Server) {
+		s.welcome = welcome
+	}
+}
+
+// New starts a server answering the commands in responses with their reply.
+// Replies are sent as they are and have to be formatted like Bird does (see Reply and Error)
+func New(responses map[string][]byte, opts ...Option) (*Server, error) {
+	dir, err := ioutil.TempDir("", "bird")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		Path:      filepath.Join(dir, "bird.ctl"),
+		dir:       dir,
+		welcome:   []byte(DefaultWelcome),
+		responses: make(map[string][]byte),
+		conns:     make(map[net.Conn]struct{}),
+	}
+	for cmd, reply := range responses {
+		s.responses[cmd] = reply
+	}
+	for _, o := range opts {
+		o(s)
+	}
+
+	s.listener, err = net.Listen("unix", s.Path)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+// Handle registers the reply for cmd, replacing the reply registered before
+func (s *Server) Handle(cmd string, reply []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.responses[cmd] = reply
+}
+
+// Close stops the server, closes all connections and removes the socket
+func (s *Server) Close() error {
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	os.RemoveAll(s.dir)
+
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	if _, err := conn.Write(s.welcome); err != nil {
+		return
+	}
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		if _, err := conn.Write(s.reply(strings.TrimSpace(scanner.Text()))); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) reply(cmd string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reply, found := s.responses[cmd]
+	if !found {
+		return Error(9001, "syntax error")
+	}
+
+	return reply
+}
+
+// Reply formats lines as a reply of Bird with code completed by the line `0000 `,
+// e.g. Reply(1002, "device1 Device ---  up")
+func Reply(code int, lines ...string) []byte {
+	var b strings.Builder
+	for i, l := range lines {
+		if i == 0 {
+			fmt.Fprintf(&b, "%04d-%s\n", code, l)
+			continue
+		}
+
+		fmt.Fprintf(&b, " %s\n", l)
+	}
+	b.WriteString("0000 \n")
+
+	return []byte(b.String())
+}
+
+// Error formats a single line reply of Bird with code, e.g. Error(8003, "No protocols match")
+func Error(code int, msg string) []byte {
+	return []byte(fmt.Sprintf("%04d %s\n", code, msg))
+}
@@ -0,0 +1,77 @@
+package testserver
+
+import (
+	"testing"
+
+	"github.com/czerwonk/testutils/assert"
+	birdsocket "github.com/kargh/bird_socket"
+)
+
+// TestServer verifies that registered commands are answered with their reply
+func TestServer(t *testing.T) {
+	srv, err := New(map[string][]byte{
+		"show status": []byte("1000-BIRD 2.0.8\n0013 Daemon is up and running\n"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	srv.Handle("show protocols", Reply(1002, "device1    Device     ---        up     2021-02-28"))
+
+	s := birdsocket.NewSocket(srv.Path)
+	welcome, err := s.Connect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	assert.StringEqual("welcome", DefaultWelcome, string(welcome), t)
+
+	st, err := s.ShowStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringEqual("version", "2.0.8", st.Version, t)
+
+	protocols, err := s.ShowProtocols()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.IntEqual("protocols", 1, len(protocols), t)
+	assert.StringEqual("name", "device1", protocols[0].Name, t)
+
+	_, err = s.QueryChecked("show foo")
+	birdErr, ok := err.(*birdsocket.BirdError)
+	assert.True("*BirdError returned", ok, t)
+	assert.IntEqual("code", birdsocket.CodeSyntaxError, birdErr.Code, t)
+}
+
+// TestServerWelcome verifies the welcome message set by WithWelcome
+func TestServerWelcome(t *testing.T) {
+	srv, err := New(nil, WithWelcome([]byte("0001 BIRD 1.6.4 ready.\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	s := birdsocket.NewSocket(srv.Path)
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	major, minor, _, err := s.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("major", 1, major, t)
+	assert.IntEqual("minor", 6, minor, t)
+}
+
+// TestReply verifies the formatting of replies
+func TestReply(t *testing.T) {
+	assert.StringEqual("reply", "1002-foo\n bar\n0000 \n", string(Reply(1002, "foo", "bar")), t)
+	assert.StringEqual("error", "8003 No protocols match\n", string(Error(8003, "No protocols match")), t)
+}
@@ -0,0 +1,105 @@
+package birdsocket
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var bannerVersionRegex *regexp.Regexp
+
+func init() {
+	// e.g. `0001 BIRD 2.0.8 ready.`
+	bannerVersionRegex = regexp.MustCompile(`^\d{4}[ -]BIRD (v?\d+(?:\.\d+)*)`)
+}
+
+// Welcome is the parsed welcome message sent by Bird after connecting,
+// e.g. `0001 BIRD 2.0.7 ready.`
+type Welcome struct {
+	Code    int
+	Version string
+	Message string
+}
+
+// ConnectInfo connects to the Bird socket like Connect and returns the parsed welcome message.
+// The connection is closed if the welcome message can not be parsed
+func (s *BirdSocket) ConnectInfo() (*Welcome, error) {
+	b, err := s.Connect(true)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := parseWelcome(b)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func parseWelcome(b []byte) (*Welcome, error) {
+	t := ParseReply(b).terminal()
+	if t == nil {
+		return nil, fmt.Errorf("invalid welcome message: %q", b)
+	}
+
+	return &Welcome{Code: t.Code, Version: parseBanner(b), Message: t.Message}, nil
+}
+
+// Version returns the version of the connected Bird daemon.
+// It is taken from the welcome banner read by Connect or,
+// if the banner was not read, from `show status`
+func (s *BirdSocket) Version() (major, minor, patch int, err error) {
+	s.mu.Lock()
+	v := s.version
+	s.mu.Unlock()
+
+	if v == "" {
+		st, err := s.ShowStatus()
+		if err != nil {
+			return 0, 0, 0, err
+		}
+
+		v = st.Version
+		s.mu.Lock()
+		s.version = v
+		s.mu.Unlock()
+	}
+
+	return parseVersion(v)
+}
+
+// IsV2 returns true if the connected Bird daemon is version 2.0 or later.
+// If the version can not be determined false is returned
+func (s *BirdSocket) IsV2() bool {
+	major, _, _, err := s.Version()
+	return err == nil && major >= 2
+}
+
+func parseBanner(welcome []byte) string {
+	m := bannerVersionRegex.FindSubmatch(welcome)
+	if m == nil {
+		return ""
+	}
+
+	return string(m[1])
+}
+
+// parseVersion parses versions like 1.6.4 or 2.0
+func parseVersion(v string) (major, minor, patch int, err error) {
+	parts := strings.Split(strings.TrimPrefix(v, "v"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, 0, 0, fmt.Errorf("invalid version: %q", v)
+	}
+
+	nums := make([]int, 3)
+	for i, p := range parts {
+		if nums[i], err = strconv.Atoi(p); err != nil || nums[i] < 0 {
+			return 0, 0, 0, fmt.Errorf("invalid version: %q", v)
+		}
+	}
+
+	return nums[0], nums[1], nums[2], nil
+}
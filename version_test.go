@@ -0,0 +1,101 @@
+package birdsocket
+
+import (
+	"testing"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestVersionFromBanner verifies the version parsed from the welcome banner of Bird 1.6 and 2.0
+func TestVersionFromBanner(t *testing.T) {
+	tests := []struct {
+		name   string
+		banner string
+		major  int
+		minor  int
+		patch  int
+		v2     bool
+	}{
+		{name: "1.6", banner: "0001 BIRD 1.6.4 ready.\n", major: 1, minor: 6, patch: 4},
+		{name: "2.0", banner: "0001 BIRD 2.0.8 ready.\n", major: 2, minor: 0, patch: 8, v2: true},
+		{name: "without patch", banner: "0001 BIRD 2.13 ready.\n", major: 2, minor: 13, v2: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewSocket("", WithDialer(pipeDialer(serveRepliesWithWelcome(test.banner, nil))))
+			if _, err := s.Connect(true); err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			major, minor, patch, err := s.Version()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.IntEqual("major", test.major, major, t)
+			assert.IntEqual("minor", test.minor, minor, t)
+			assert.IntEqual("patch", test.patch, patch, t)
+			assert.True("IsV2", test.v2 == s.IsV2(), t)
+		})
+	}
+}
+
+// TestVersionFromStatus verifies that the version is queried
+// if the welcome banner does not contain it
+func TestVersionFromStatus(t *testing.T) {
+	s := NewSocket("", WithDialer(pipeDialer(serveRepliesWithWelcome("0001 Ready.\n", map[string]string{
+		"show status": "1000-BIRD 2.0.8\n0013 Daemon is up and running\n",
+	}))))
+	if _, err := s.Connect(false); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	major, minor, patch, err := s.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("major", 2, major, t)
+	assert.IntEqual("minor", 0, minor, t)
+	assert.IntEqual("patch", 8, patch, t)
+	assert.True("IsV2", s.IsV2(), t)
+}
+
+// TestParseVersionInvalid verifies that malformed versions are rejected
+func TestParseVersionInvalid(t *testing.T) {
+	for _, v := range []string{"", "2", "2.x", "1.2.3.4", "-1.0"} {
+		_, _, _, err := parseVersion(v)
+		assert.True(v+" rejected", err != nil, t)
+	}
+}
+
+// TestConnectInfo verifies the welcome message parsed for Bird 1.6 and 2.0
+func TestConnectInfo(t *testing.T) {
+	tests := []struct {
+		banner  string
+		version string
+		message string
+	}{
+		{banner: "0001 BIRD 1.6.4 ready.\n", version: "1.6.4", message: "BIRD 1.6.4 ready."},
+		{banner: "0001 BIRD 2.0.8 ready.\n", version: "2.0.8", message: "BIRD 2.0.8 ready."},
+		{banner: "0001 BIRD v2.15.1 ready.\n", version: "v2.15.1", message: "BIRD v2.15.1 ready."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			s := NewSocket("", WithDialer(pipeDialer(serveRepliesWithWelcome(test.banner, nil))))
+			w, err := s.ConnectInfo()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			assert.IntEqual("code", CodeWelcome, w.Code, t)
+			assert.StringEqual("version", test.version, w.Version, t)
+			assert.StringEqual("message", test.message, w.Message, t)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package birdsocket
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// defaultWatchInterval is used by WatchProtocols for invalid intervals
+const defaultWatchInterval = time.Second
+
+// WatchProtocols queries the protocol instances every interval and sends them
+// whenever they changed since the last successful query (starting with the first one).
+// Failed queries are sent on the error channel without stopping the watch.
+// Both channels have to be drained and are closed once ctx is done.
+// Intervals of 0 or less are replaced by an interval of one second
+func (s *BirdSocket) WatchProtocols(ctx context.Context, interval time.Duration) (<-chan []Protocol, <-chan error) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	protocols := make(chan []Protocol)
+	errs := make(chan error)
+
+	go func() {
+		defer close(protocols)
+		defer close(errs)
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		var last []Protocol
+		for {
+			p, err := s.ShowProtocols()
+			switch {
+			case err != nil:
+				select {
+				case errs <- err:
+				case <-ctx.Done():
+					return
+				}
+			case last == nil || !reflect.DeepEqual(p, last):
+				last = p
+				select {
+				case protocols <- p:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return protocols, errs
+}
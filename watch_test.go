@@ -0,0 +1,75 @@
+package birdsocket
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/czerwonk/testutils/assert"
+)
+
+// TestWatchProtocols simulate a scenario in which the state of a protocol
+// changes between polls and a poll fails in between
+func TestWatchProtocols(t *testing.T) {
+	replies := []string{
+		"1002-bgp1       BGP        ---        up     2021-02-28 09:00:05  Established\n0000 \n",
+		"1002-bgp1       BGP        ---        up     2021-02-28 09:00:05  Established\n0000 \n",
+		"8008 runtime error\n",
+		"1002-bgp1       BGP        ---        start  2021-02-28 09:10:00  Active\n0000 \n",
+	}
+	s := NewSocket("", WithReadDeadline(5*time.Second), WithDialer(pipeDialer(func(conn net.Conn) {
+		conn.Write([]byte(fakeWelcome))
+
+		scanner := bufio.NewScanner(conn)
+		for i := 0; scanner.Scan(); i++ {
+			if i >= len(replies) {
+				i = len(replies) - 1
+			}
+			conn.Write([]byte(replies[i]))
+		}
+	})))
+	if _, err := s.Connect(true); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	protocols, errs := s.WatchProtocols(ctx, 10*time.Millisecond)
+
+	p := <-protocols
+	assert.StringEqual("state", "up", p[0].State, t)
+
+	err := <-errs
+	_, ok := err.(*BirdError)
+	assert.True("*BirdError received", ok, t)
+
+	p = <-protocols
+	assert.StringEqual("state", "start", p[0].State, t)
+
+	cancel()
+	for range protocols {
+	}
+	for range errs {
+	}
+}
+
+// TestWatchProtocolsInvalidInterval verifies that watching with an interval of 0 does not panic
+func TestWatchProtocolsInvalidInterval(t *testing.T) {
+	s := connectFakeBird(t, map[string]string{
+		"show protocols": "1002-bgp1       BGP        ---        up     2021-02-28 09:00:05  Established\n0000 \n",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	protocols, errs := s.WatchProtocols(ctx, 0)
+
+	p := <-protocols
+	assert.StringEqual("state", "up", p[0].State, t)
+
+	cancel()
+	for range protocols {
+	}
+	for range errs {
+	}
+}